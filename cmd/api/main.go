@@ -2,21 +2,28 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 
 	"quavixAI/internal/config"
 	"quavixAI/internal/db"
+	"quavixAI/internal/server"
 
 	// modules
 	authModule "quavixAI/internal/modules/auth"
 	chatModule "quavixAI/internal/modules/chat"
+	ingestModule "quavixAI/internal/modules/ingest"
 	llmModule "quavixAI/internal/modules/llm"
+	embeddingModule "quavixAI/internal/modules/llm/embedding"
+	promptModule "quavixAI/internal/modules/prompt"
+	userModule "quavixAI/internal/modules/user"
 	vectorModule "quavixAI/internal/modules/vector"
 
 	// middleware
 	"quavixAI/internal/middleware"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
 )
 
 func main() {
@@ -43,14 +50,37 @@ func main() {
 		log.Fatalf("redis error: %v", err)
 	}
 
+	// Every stored vector is stamped with this model; changing it requires
+	// a re-embedding job (POST /admin/vector/reembed).
+	embeddingModel := "stub"
+
 	// ==============================
-	// Vector Store (pgvector)
+	// Vector Store (pgvector / hnsw)
 	// ==============================
-	vectorStore := vectorModule.NewPgVectorStore(pg, 384) // 384 = embedding dim from schema
+	vectorStore, err := vectorModule.New(vectorModule.Config{
+		Backend:      cfg.VectorBackend,
+		Dimension:    384, // 384 = embedding dim from schema
+		Model:        embeddingModel,
+		DB:           pg,
+		Path:         cfg.VectorPath,
+		SaveInterval: cfg.VectorSaveInterval,
+	})
+	if err != nil {
+		log.Fatalf("vector store error: %v", err)
+	}
 	if err := vectorStore.Init(context.Background()); err != nil {
 		log.Fatalf("vector init error: %v", err)
 	}
 
+	// Named collections live in Postgres regardless of the default backend
+	vectorCollections := vectorModule.NewCollectionManager(pg)
+	if err := vectorCollections.Init(context.Background(), 384, embeddingModel); err != nil {
+		log.Fatalf("vector collections init error: %v", err)
+	}
+	if pgStore, ok := vectorStore.(*vectorModule.PgVectorStore); ok {
+		vectorCollections.Attach(vectorModule.DefaultCollection, pgStore)
+	}
+
 	// ==============================
 	// LLM Manager
 	// ==============================
@@ -62,11 +92,26 @@ func main() {
 		log.Fatalf("llm error: %v", err)
 	}
 
+	embeddingService := embeddingModule.NewService(llmManager, embeddingModel, 384)
+
+	reembedder := vectorModule.NewReembedder(vectorModule.ReembedConfig{
+		DB:          pg,
+		Collections: vectorCollections,
+		Embedder:    embeddingService,
+	})
+	if err := reembedder.Init(context.Background()); err != nil {
+		log.Fatalf("re-embed init error: %v", err)
+	}
+	if err := reembedder.Recover(context.Background()); err != nil {
+		log.Fatalf("re-embed recover error: %v", err)
+	}
+
 	// ==============================
 	// Repositories
 	// ==============================
 	authRepo := authModule.NewRepository(pg)
 	chatRepo := chatModule.NewRepository(pg)
+	userRepo := userModule.NewRepository(sqlx.NewDb(pg, "postgres"))
 
 	// ==============================
 	// Services
@@ -74,24 +119,92 @@ func main() {
 	jwtSvc := authModule.NewJWT(cfg.JWTSecret) // <-- real JWT constructor
 	authService := authModule.NewService(authRepo, jwtSvc)
 
-	memoryEngine := chatModule.NewMemoryEngine(rdsClient, vectorStore, llmManager)
+	ingestPipeline := ingestModule.NewPipeline(ingestModule.Config{
+		Store:    vectorStore,
+		Embedder: embeddingService,
+	})
+
+	memoryGraph := chatModule.NewGraphStore(pg)
+	if err := memoryGraph.Init(context.Background()); err != nil {
+		log.Fatalf("memory graph init error: %v", err)
+	}
+
+	memoryEngine := chatModule.NewMemoryEngine(chatModule.MemoryConfig{
+		Redis:    rdsClient,
+		Vector:   vectorStore,
+		LLM:      llmManager,
+		Reranker: chatModule.NewLLMReranker(llmManager, promptModule.NewBuilder(), chatModule.NewLexicalReranker()),
+
+		SessionMaxLen: cfg.SessionMaxLen,
+		SessionTTL:    cfg.SessionTTL,
+
+		Graph: memoryGraph,
+	})
 
 	chatService := chatModule.NewService(chatModule.ServiceConfig{
-		Repo:      chatRepo,
-		LLM:       llmManager,
-		Vector:    vectorStore,
-		Memory:    memoryEngine,
+		Repo:   chatRepo,
+		LLM:    llmManager,
+		Vector: vectorStore,
+		Memory: memoryEngine,
+
+		// critic/proposer/judge review of every extracted root cause
+		Debate: chatModule.DebateConfig{
+			Enabled: cfg.RootCauseDebate,
+			Rounds:  cfg.DebateRounds,
+		},
+
+		// runbooks and past incidents 5-Why answers must cite
+		Evidence: chatModule.EvidenceConfig{Limit: cfg.EvidenceLimit},
+
+		// earlier incidents of the tenant, and when to reuse their analysis
+		Similar: chatModule.SimilarConfig{
+			MinScore:      cfg.SimilarMinScore,
+			ReuseMinScore: cfg.ReuseMinScore,
+		},
+
 		FiveWhy:   true,
 		Evaluator: true,
 		RootCause: true,
 		Reframer:  true,
 	})
 
+	// Async 5-Why jobs (POST /chat/5why?async=true)
+	jobQueue := chatModule.NewJobQueue(chatModule.JobConfig{
+		Redis:         rdsClient,
+		Service:       chatService,
+		Workers:       cfg.JobWorkers,
+		WebhookSecret: cfg.WebhookSecret,
+	})
+	jobQueue.Start(context.Background())
+
+	// Deleting a profile erases the user's chat, memory and vector data
+	userService := userModule.NewService(userRepo, chatService)
+
+	// Retention: evict old / excess / unimportant memory documents
+	retentionWorker, err := vectorModule.NewRetentionWorker(vectorModule.RetentionConfig{
+		Store:    vectorStore,
+		Policies: chatModule.DefaultRetentionPolicies(),
+		Interval: cfg.RetentionInterval,
+	})
+	if err != nil {
+		log.Fatalf("retention error: %v", err)
+	}
+	retentionWorker.Start(context.Background())
+
 	// ==============================
 	// Handlers
 	// ==============================
 	authHandler := authModule.NewHandler(authService)
-	chatHandler := chatModule.NewHandler(chatService)
+	chatHandler := chatModule.NewHandler(chatService, jobQueue)
+	userHandler := userModule.NewHandler(userService)
+	vectorHandler := vectorModule.NewHandler(vectorModule.HandlerConfig{
+		Store:       vectorStore,
+		Embedder:    embeddingService,
+		Collections: vectorCollections,
+		Reembedder:  reembedder,
+		Retention:   retentionWorker,
+	})
+	ingestHandler := ingestModule.NewHandler(ingestPipeline)
 
 	// ==============================
 	// Gin Router
@@ -113,20 +226,67 @@ func main() {
 
 	// User
 	protected.GET("/me", authHandler.GetCurrentUser)
+	protected.GET("/profile", userHandler.GetProfile)
+	protected.PUT("/profile", userHandler.UpdateProfile)
+	protected.DELETE("/profile", userHandler.DeleteProfile)
 
 	// Chat / AI
 	protected.POST("/chat", chatHandler.Chat)
 	protected.POST("/chat/5why", chatHandler.FiveWhy)
+	protected.GET("/chat/5why/similar", chatHandler.SimilarIncidents)
+	protected.POST("/chat/5why/start", chatHandler.StartFiveWhy)
+	protected.POST("/chat/5why/{id}/answer", chatHandler.AnswerFiveWhy)
+	protected.POST("/chat/5why/{id}/finalize", chatHandler.FinalizeFiveWhy)
+	protected.GET("/chat/5why/{id}", chatHandler.GetFiveWhyRun)
+	protected.POST("/chat/5why/{id}/resume", chatHandler.ResumeFiveWhy)
+	protected.GET("/jobs/{id}", chatHandler.GetJob)
+
+	// Analysis methodologies (5why, fishbone, fault_tree, a3)
+	protected.GET("/analysis", chatHandler.Methods)
+	protected.POST("/analysis/{method}", chatHandler.Analyze)
 	protected.POST("/chat/root-cause", chatHandler.RootCause)
 	protected.POST("/chat/reframe", chatHandler.Reframe)
 	protected.POST("/chat/memory/compress", chatHandler.CompressSession)
 	protected.POST("/chat/memory/recall", chatHandler.Recall)
+	protected.GET("/chat/sessions/{id}", chatHandler.GetSession)
+	protected.GET("/memory/graph", chatHandler.Graph)
+
+	// Vector
+	protected.GET("/vector/collections", vectorHandler.ListCollections)
+	protected.GET("/vector/collections/{name}", vectorHandler.GetCollection)
+
+	// Admin
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireRole("admin"))
+
+	// imported records keep their meta, owner tags included
+	admin.POST("/vector/import", vectorHandler.Import)
+	admin.POST("/vector/collections", vectorHandler.CreateCollection)
+	admin.DELETE("/vector/collections/{name}", vectorHandler.DropCollection)
+	admin.POST("/vector/reembed", vectorHandler.StartReembed)
+	admin.GET("/vector/reembed", vectorHandler.ListReembeds)
+	admin.GET("/vector/reembed/{id}", vectorHandler.GetReembed)
+	admin.POST("/vector/reembed/{id}/resume", vectorHandler.ResumeReembed)
+	admin.POST("/vector/reembed/{id}/cancel", vectorHandler.CancelReembed)
+	admin.GET("/vector/retention", vectorHandler.RetentionReport)
+	admin.POST("/vector/retention/run", vectorHandler.RunRetention)
+
+	// Knowledge base ingestion
+	admin.POST("/ingest", ingestHandler.Ingest)
+	admin.DELETE("/ingest", ingestHandler.Remove)
 
 	// ==============================
 	// Start server
 	// ==============================
 	log.Println("API running on :" + cfg.Port)
-	if err := r.Run(":" + cfg.Port); err != nil {
+	if err := server.Run(server.Config{Port: cfg.Port}, r); err != nil {
 		log.Fatal(err)
 	}
+
+	// the hnsw backend only reaches disk when saved
+	if c, ok := vectorStore.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("vector store close error: %v", err)
+		}
+	}
 }
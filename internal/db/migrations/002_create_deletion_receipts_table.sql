@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE deletion_receipts (
+    id VARCHAR(36) PRIMARY KEY,
+    user_id VARCHAR(36) NOT NULL,
+    results JSONB NOT NULL DEFAULT '[]',
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX deletion_receipts_user_id_idx ON deletion_receipts (user_id);
+
+-- +goose Down
+DROP TABLE deletion_receipts;
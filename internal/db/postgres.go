@@ -58,6 +58,8 @@ func (p *PostgresClient) InitSchema(ctx context.Context) error {
 			updated_at TIMESTAMPTZ DEFAULT NOW()
 		);`,
 
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';`,
+
 		`CREATE TABLE IF NOT EXISTS chat_messages (
 			id TEXT PRIMARY KEY,
 			session_id TEXT,
@@ -78,17 +80,55 @@ func (p *PostgresClient) InitSchema(ctx context.Context) error {
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		);`,
 
-		`CREATE TABLE IF NOT EXISTS vector_memory (
+		`ALTER TABLE fivewhy_sessions ADD COLUMN IF NOT EXISTS tree JSONB;`,
+
+		`CREATE TABLE IF NOT EXISTS fivewhy_runs (
 			id TEXT PRIMARY KEY,
-			content TEXT,
-			embedding VECTOR(384),
-			metadata JSONB,
+			user_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			question TEXT NOT NULL,
+			status TEXT NOT NULL,
+			level INT NOT NULL,
+			current_question TEXT NOT NULL DEFAULT '',
+			steps JSONB NOT NULL DEFAULT '[]',
+			result JSONB,
+			version INT NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS fivewhy_runs_user_idx ON fivewhy_runs (user_id);`,
+
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'interactive';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS completed_stages JSONB NOT NULL DEFAULT '[]';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS failed_stage TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS error TEXT NOT NULL DEFAULT '';`,
+
+		`CREATE TABLE IF NOT EXISTS analyses (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL,
+			problem TEXT NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			result JSONB NOT NULL,
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		);`,
 
-		`CREATE INDEX IF NOT EXISTS vector_memory_embedding_idx
-		 ON vector_memory USING ivfflat (embedding vector_l2_ops)
-		 WITH (lists = 100);`,
+		`CREATE INDEX IF NOT EXISTS analyses_user_idx ON analyses (user_id);`,
+
+		`CREATE TABLE IF NOT EXISTS deletion_receipts (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			results JSONB NOT NULL DEFAULT '[]',
+			requested_at TIMESTAMPTZ NOT NULL,
+			completed_at TIMESTAMPTZ NOT NULL
+		);`,
+
+		// vector tables (vector_memory and named collections) are created
+		// by the vector module with their own dimension and index settings
 	}
 
 	for _, q := range queries {
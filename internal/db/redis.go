@@ -2,11 +2,16 @@ package db
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// ErrNil is returned by Get when the key does not exist.
+var ErrNil = redis.Nil
+
 type RedisClient struct {
 	Client *redis.Client
 }
@@ -44,3 +49,202 @@ func (r *RedisClient) Get(ctx context.Context, key string) (string, error) {
 func (r *RedisClient) Del(ctx context.Context, key string) error {
 	return r.Client.Del(ctx, key).Err()
 }
+
+// DeleteKeys removes every given key and returns how many existed.
+func (r *RedisClient) DeleteKeys(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return r.Client.Del(ctx, keys...).Result()
+}
+
+func (r *RedisClient) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return r.Client.Expire(ctx, key, ttl).Err()
+}
+
+// ================================
+// Lists
+// ================================
+
+// AppendList pushes values onto key, keeps only the newest maxLen
+// entries (0 = unbounded) and refreshes the TTL, all in one MULTI/EXEC
+// so concurrent writers never lose entries. It returns the list length.
+func (r *RedisClient) AppendList(ctx context.Context, key string, maxLen int64, ttl time.Duration, values ...interface{}) (int64, error) {
+	var push *redis.IntCmd
+	var length *redis.IntCmd
+
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		push = pipe.RPush(ctx, key, values...)
+		if maxLen > 0 {
+			pipe.LTrim(ctx, key, -maxLen, -1)
+		}
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+		length = pipe.LLen(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := push.Err(); err != nil {
+		return 0, err
+	}
+	return length.Val(), nil
+}
+
+// ListPage reads entries start..stop (inclusive, negative counts from
+// the end, as in LRANGE) together with the list length, atomically.
+func (r *RedisClient) ListPage(ctx context.Context, key string, start, stop int64) ([]string, int64, error) {
+	var items *redis.StringSliceCmd
+	var length *redis.IntCmd
+
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		length = pipe.LLen(ctx, key)
+		items = pipe.LRange(ctx, key, start, stop)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return items.Val(), length.Val(), nil
+}
+
+// foldListHead drops the first ARGV[1] entries of KEYS[1] and stores
+// ARGV[3] at KEYS[2], but only if the entry at ARGV[1]-1 is still ARGV[2]
+// (i.e. nothing trimmed the head in the meantime).
+var foldListHead = redis.NewScript(`
+if redis.call('LINDEX', KEYS[1], tonumber(ARGV[1]) - 1) ~= ARGV[2] then
+	return 0
+end
+redis.call('LTRIM', KEYS[1], tonumber(ARGV[1]), -1)
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[2], ARGV[3], 'PX', tonumber(ARGV[4]))
+else
+	redis.call('SET', KEYS[2], ARGV[3])
+end
+return 1
+`)
+
+// FoldListHead atomically replaces the first count entries of list with
+// value stored at key. last must equal the entry at index count-1; if it
+// does not, nothing is changed and false is returned.
+func (r *RedisClient) FoldListHead(ctx context.Context, list string, count int64, last, key, value string, ttl time.Duration) (bool, error) {
+	n, err := foldListHead.Run(ctx, r.Client, []string{list, key}, count, last, value, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ================================
+// Sets
+// ================================
+
+// AddToSet adds members to key and refreshes its TTL in one MULTI/EXEC.
+func (r *RedisClient) AddToSet(ctx context.Context, key string, ttl time.Duration, members ...interface{}) error {
+	_, err := r.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SAdd(ctx, key, members...)
+		if ttl > 0 {
+			pipe.Expire(ctx, key, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisClient) SetMembers(ctx context.Context, key string) ([]string, error) {
+	return r.Client.SMembers(ctx, key).Result()
+}
+
+func (r *RedisClient) RemoveFromSet(ctx context.Context, key string, members ...interface{}) error {
+	return r.Client.SRem(ctx, key, members...).Err()
+}
+
+// ================================
+// Ownership
+// ================================
+
+// claimKey sets KEYS[1] to ARGV[1] unless it already holds another
+// value, refreshes the TTL and returns the holder.
+var claimKey = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if not holder then
+	holder = ARGV[1]
+	redis.call('SET', KEYS[1], holder)
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], tonumber(ARGV[2]))
+end
+return holder
+`)
+
+// Claim records value as the holder of key if nobody holds it yet and
+// returns the current holder. The TTL is refreshed either way.
+func (r *RedisClient) Claim(ctx context.Context, key, value string, ttl time.Duration) (string, error) {
+	return claimKey.Run(ctx, r.Client, []string{key}, value, ttl.Milliseconds()).Text()
+}
+
+// ================================
+// Locks
+// ================================
+
+var releaseLock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// TryLock takes a best-effort lock that expires after ttl. ok is false
+// when someone else holds it. unlock only releases the lock if it is
+// still ours.
+func (r *RedisClient) TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error) {
+	token := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	ok, err = r.Client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return func() {}, false, err
+	}
+
+	return func() {
+		_ = releaseLock.Run(context.Background(), r.Client, []string{key}, token).Err()
+	}, true, nil
+}
+
+// ================================
+// Queues
+// ================================
+
+// Enqueue pushes values onto the tail of queue.
+func (r *RedisClient) Enqueue(ctx context.Context, queue string, values ...interface{}) error {
+	return r.Client.LPush(ctx, queue, values...).Err()
+}
+
+// Dequeue waits up to timeout for the oldest entry of queue and moves it
+// onto processing atomically, so it is not lost if the consumer dies
+// before calling Ack. It returns ErrNil on timeout.
+func (r *RedisClient) Dequeue(ctx context.Context, queue, processing string, timeout time.Duration) (string, error) {
+	return r.Client.BLMove(ctx, queue, processing, "RIGHT", "LEFT", timeout).Result()
+}
+
+// Ack removes a finished entry from processing.
+func (r *RedisClient) Ack(ctx context.Context, processing, value string) error {
+	return r.Client.LRem(ctx, processing, 1, value).Err()
+}
+
+// Requeue moves every entry left in processing back to the front of
+// queue, in their original order, and returns how many were moved.
+func (r *RedisClient) Requeue(ctx context.Context, processing, queue string) (int64, error) {
+	var n int64
+	for {
+		err := r.Client.LMove(ctx, processing, queue, "LEFT", "RIGHT").Err()
+		if errors.Is(err, redis.Nil) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
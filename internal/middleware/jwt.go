@@ -55,7 +55,24 @@ func JWTAuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
+		role, _ := claims["role"].(string)
+		tenantID, _ := claims["tid"].(string) // optional
+
 		c.Set("userID", userID)
+		c.Set("tenantID", tenantID)
+		c.Set("role", role)
+		c.Next()
+	}
+}
+
+// RequireRole must run after JWTAuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -3,7 +3,7 @@ package auth
 import jwt "github.com/golang-jwt/jwt/v5"
 
 type JWTService interface {
-	Generate(uid, role string) (string, error)
+	Generate(uid, role, tenantID string) (string, error)
 }
 
 type jwtService struct {
@@ -14,11 +14,14 @@ func NewJWT(secret string) JWTService {
 	return &jwtService{secret: []byte(secret)}
 }
 
-func (j *jwtService) Generate(uid, role string) (string, error) {
+func (j *jwtService) Generate(uid, role, tenantID string) (string, error) {
 	claims := jwt.MapClaims{
 		"uid":  uid,
 		"role": role,
 	}
+	if tenantID != "" {
+		claims["tid"] = tenantID // read by middleware.JWTAuthMiddleware
+	}
 	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return t.SignedString(j.secret)
 }
@@ -39,7 +39,7 @@ func (s *Service) Signup(email, password, name string) (*user.User, string, erro
 		return nil, "", err
 	}
 
-	token, err := s.jwt.Generate(u.ID, u.Role)
+	token, err := s.jwt.Generate(u.ID, u.Role, u.TenantID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -57,7 +57,7 @@ func (s *Service) Login(email, password string) (*user.User, string, error) {
 		return nil, "", errors.New("invalid credentials")
 	}
 
-	token, err := s.jwt.Generate(u.ID, u.Role)
+	token, err := s.jwt.Generate(u.ID, u.Role, u.TenantID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"context"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/types"
+)
+
+// ================================
+// A3 Report
+// ================================
+
+const MethodA3 = "a3"
+
+// a3RootCauseSection is the section an A3 report's summary comes from.
+const a3RootCauseSection = "Root Cause Analysis"
+
+type A3Report struct {
+	Problem  string            `json:"problem"`
+	Sections []types.A3Section `json:"sections"`
+}
+
+func (r *A3Report) Summary() string {
+	for _, s := range r.Sections {
+		if s.Title == a3RootCauseSection {
+			return s.Content
+		}
+	}
+	return ""
+}
+
+// A3 writes the report one section at a time, in prompt.A3Sections
+// order. Each section sees the ones before it, so they cannot run in
+// parallel.
+type A3 struct {
+	llm    *llm.Manager
+	prompt prompt.Builder
+}
+
+func NewA3(cfg MethodConfig) *A3 {
+	cfg.defaults()
+	return &A3{
+		llm:    cfg.LLM,
+		prompt: cfg.Prompt,
+	}
+}
+
+func (a *A3) Name() string {
+	return MethodA3
+}
+
+func (a *A3) Analyze(ctx context.Context, req AnalysisRequest) (Analysis, error) {
+	report := &A3Report{
+		Problem:  req.Problem,
+		Sections: make([]types.A3Section, 0, len(prompt.A3Sections)),
+	}
+
+	for _, section := range prompt.A3Sections {
+		resp, err := a.llm.Generate(ctx, llm.Request{
+			Mode:   llm.ModePlanning,
+			Prompt: a.prompt.BuildA3SectionPrompt(req.Problem, section.Title, report.Sections),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := a.prompt.ParseA3Section(resp.Text)
+		if err != nil {
+			return nil, err
+		}
+		report.Sections = append(report.Sections, types.A3Section{
+			Title:   section.Title,
+			Content: content,
+		})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,144 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"quavixAI/internal/modules/types"
+)
+
+// ================================
+// Checkpointed 5-Why Runs
+// ================================
+
+// runStaleAfter is how long a run may sit in "running" without a
+// checkpoint before it is presumed abandoned (e.g. the process died)
+// and may be resumed.
+const runStaleAfter = 15 * time.Minute
+
+// finishStage names the bookkeeping done after the last pipeline stage.
+const finishStage = "finish"
+
+// RunError is returned when an automatic run fails. Everything up to
+// Stage is checkpointed under RunID and can be resumed.
+type RunError struct {
+	RunID string
+	Stage string
+	Err   error
+}
+
+func (e *RunError) Error() string { return e.Err.Error() }
+func (e *RunError) Unwrap() error { return e.Err }
+
+// ResumeFiveWhy continues a failed (or abandoned) automatic run from
+// its last completed stage. A finalized run returns its result as is.
+func (s *Service) ResumeFiveWhy(ctx context.Context, owner types.Owner, id string) (*FiveWhySession, error) {
+	return s.resumeRun(ctx, owner, id, false)
+}
+
+// resumeRun resumes run id. With takeover, a run still marked running
+// is resumed at once instead of after runStaleAfter; the claim below
+// still keeps two resumes from both proceeding.
+func (s *Service) resumeRun(ctx context.Context, owner types.Owner, id string, takeover bool) (*FiveWhySession, error) {
+	run, err := s.GetFiveWhyRun(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Mode != RunAuto {
+		return nil, ErrRunNotResumable
+	}
+
+	switch run.Status {
+	case RunFinalized:
+		return run.Result, nil
+	case RunRunning:
+		if !takeover && time.Since(run.UpdatedAt) < runStaleAfter {
+			return nil, ErrRunInProgress
+		}
+	}
+	ctx = types.WithOwner(ctx, owner)
+
+	if run.Result == nil {
+		run.Result = newRunSession(run)
+	}
+	run.Status = RunRunning
+	run.FailedStage = ""
+	run.Error = ""
+	run.UpdatedAt = time.Now()
+
+	// claims the run: a concurrent resume gets ErrRunConflict
+	if err := s.repo.UpdateFiveWhyRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	return s.executeRun(ctx, owner, run)
+}
+
+// executeRun drives run's pipeline, skipping the stages it already
+// completed and checkpointing every stage that completes now.
+func (s *Service) executeRun(ctx context.Context, owner types.Owner, run *FiveWhyRun) (*FiveWhySession, error) {
+	session := run.Result
+
+	cp := &Checkpoint{Done: make(map[string]bool, len(run.CompletedStages))}
+	for _, stage := range run.CompletedStages {
+		cp.Done[stage] = true
+	}
+	if s.repo != nil {
+		cp.Save = func(ctx context.Context, stage string) error {
+			run.CompletedStages = append(run.CompletedStages, stage)
+			run.UpdatedAt = time.Now()
+			return s.repo.UpdateFiveWhyRun(ctx, run)
+		}
+	}
+
+	err := s.orchestrator.Execute(ctx, owner, run.Question, session, cp)
+	if err == nil {
+		if ferr := s.finishFiveWhy(ctx, owner, run.Question, session); ferr != nil {
+			err = &StageError{Stage: finishStage, Err: ferr}
+		}
+	}
+	if err != nil {
+		return nil, s.failRun(ctx, run, err)
+	}
+
+	run.Status = RunFinalized
+	run.UpdatedAt = time.Now()
+	if s.repo != nil {
+		if err := s.repo.UpdateFiveWhyRun(ctx, run); err != nil {
+			log.Printf("5-why run %s: finalize checkpoint failed: %v", run.ID, err)
+		}
+	}
+
+	return session, nil
+}
+
+// failRun records the failed stage and its error on the run so GET
+// shows them, even when the request itself was cancelled.
+func (s *Service) failRun(ctx context.Context, run *FiveWhyRun, err error) error {
+	var se *StageError
+	if errors.As(err, &se) {
+		run.FailedStage = se.Stage
+	}
+	run.Status = RunFailed
+	run.Error = err.Error()
+	run.UpdatedAt = time.Now()
+
+	if s.repo == nil {
+		return err
+	}
+	if uerr := s.repo.UpdateFiveWhyRun(context.WithoutCancel(ctx), run); uerr != nil {
+		log.Printf("5-why run %s: failure checkpoint failed: %v", run.ID, uerr)
+	}
+	return &RunError{RunID: run.ID, Stage: run.FailedStage, Err: err}
+}
+
+func newRunSession(run *FiveWhyRun) *FiveWhySession {
+	return &FiveWhySession{
+		SessionID: run.SessionID,
+		RunID:     run.ID,
+		Steps:     []types.FiveWhyStep{},
+		CreatedAt: run.CreatedAt,
+	}
+}
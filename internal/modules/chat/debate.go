@@ -0,0 +1,138 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/types"
+)
+
+// ================================
+// Root Cause Debate
+// ================================
+
+const DefaultDebateRounds = 2
+
+// DebateConfig enables the adversarial check of extracted root causes.
+// Each round a critic challenges the current root cause and, unless it
+// concedes, the proposer revises it; after at most Rounds a judge rules.
+type DebateConfig struct {
+	Enabled bool
+	Rounds  int
+}
+
+func (c *DebateConfig) defaults() {
+	if c.Rounds <= 0 {
+		c.Rounds = DefaultDebateRounds
+	}
+}
+
+// Debate puts rc through critique, revision and a final ruling against
+// the evidence in steps; the judge is skipped when the critic concedes
+// straight away. The returned result carries the transcript and
+// every confidence change in its Debate field.
+func (o *Orchestrator) Debate(ctx context.Context, steps []types.FiveWhyStep, rc types.RootCauseResult) (*types.RootCauseResult, error) {
+	if rc.RootCause == "" {
+		return nil, errors.New("no root cause to debate")
+	}
+
+	rc.Debate = nil
+
+	debate := &types.RootCauseDebate{
+		Initial:           rc.RootCause,
+		Outcome:           "upheld",
+		Transcript:        []types.DebateTurn{},
+		ConfidenceChanges: []types.ConfidenceChange{},
+	}
+	initial := rc
+	current := rc
+	revised := false
+
+	for round := 1; round <= o.debate.Rounds; round++ {
+		debate.Rounds = round
+
+		resp, err := o.llm.Generate(ctx, llm.Request{
+			Mode:   llm.ModeAnalysis,
+			Prompt: o.prompt.BuildCritiquePrompt(steps, current),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var critique types.Critique
+		if err := o.prompt.ParseCritique(resp.Text, &critique); err != nil {
+			return nil, err
+		}
+		debate.Transcript = append(debate.Transcript, types.DebateTurn{
+			Round:      round,
+			Role:       types.RoleCritic,
+			Content:    critique.Summary,
+			Objections: critique.Objections,
+		})
+		if critique.Concede {
+			break
+		}
+
+		resp, err = o.llm.Generate(ctx, llm.Request{
+			Mode:   llm.ModeDiagnosis,
+			Prompt: o.prompt.BuildRevisionPrompt(steps, current, critique),
+		})
+		if err != nil {
+			return nil, err
+		}
+		var revision types.DebateRuling
+		if err := o.prompt.ParseRuling(resp.Text, &revision); err != nil {
+			return nil, err
+		}
+		recordRuling(debate, round, types.RoleProposer, current, revision)
+		current = revision.RootCauseResult
+		revised = true
+	}
+
+	// the critic accepted the root cause as proposed: nothing to settle
+	if !revised {
+		initial.Debate = debate
+		return &initial, nil
+	}
+
+	resp, err := o.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: o.prompt.BuildJudgePrompt(steps, initial, current, debate.Transcript),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ruling types.DebateRuling
+	if err := o.prompt.ParseRuling(resp.Text, &ruling); err != nil {
+		return nil, err
+	}
+	recordRuling(debate, debate.Rounds, types.RoleJudge, current, ruling)
+
+	result := ruling.RootCauseResult
+	if result.RootCause != initial.RootCause {
+		debate.Outcome = "revised"
+	}
+	result.Debate = debate
+	groundRootCause(&result, steps)
+
+	return &result, nil
+}
+
+// recordRuling adds a proposer's or judge's turn and its confidence
+// change from the root cause it answered.
+func recordRuling(d *types.RootCauseDebate, round int, role string, from types.RootCauseResult, to types.DebateRuling) {
+	d.Transcript = append(d.Transcript, types.DebateTurn{
+		Round:      round,
+		Role:       role,
+		Content:    to.Rationale,
+		RootCause:  to.RootCause,
+		Confidence: to.Confidence,
+	})
+	d.ConfidenceChanges = append(d.ConfidenceChanges, types.ConfidenceChange{
+		Round:  round,
+		Role:   role,
+		From:   from.Confidence,
+		To:     to.Confidence,
+		Reason: to.Rationale,
+	})
+}
@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"quavixAI/internal/modules/ingest"
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+)
+
+// ================================
+// Evidence Grounding
+// ================================
+
+const (
+	DefaultEvidenceLimit = 3
+
+	// snippets are what the prompt shows and what a citation keeps
+	maxSnippetLen = 400
+)
+
+// EvidenceSource finds documents a WHY answer can cite.
+type EvidenceSource interface {
+	Evidence(ctx context.Context, owner types.Owner, query string, limit int) ([]vector.Document, error)
+}
+
+// EvidenceConfig grounds 5-Why runs in retrieved documents. A nil
+// Source leaves answers and root causes uncited.
+type EvidenceConfig struct {
+	Source EvidenceSource
+	Limit  int // documents retrieved per WHY level
+}
+
+func (c *EvidenceConfig) defaults() {
+	if c.Limit <= 0 {
+		c.Limit = DefaultEvidenceLimit
+	}
+}
+
+// evidenceSet labels the documents retrieved during one run, so a
+// document keeps the same ref across levels and branches.
+type evidenceSet struct {
+	mu   sync.Mutex
+	refs map[string]string // document ID -> ref
+}
+
+func newEvidenceSet() *evidenceSet {
+	return &evidenceSet{refs: make(map[string]string)}
+}
+
+func (e *evidenceSet) cite(docs []vector.Document) []types.Citation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make([]types.Citation, 0, len(docs))
+	for _, d := range docs {
+		ref, ok := e.refs[d.ID]
+		if !ok {
+			ref = fmt.Sprintf("D%d", len(e.refs)+1)
+			e.refs[d.ID] = ref
+		}
+		out = append(out, types.Citation{
+			Ref:        ref,
+			DocumentID: d.ID,
+			Source:     documentSource(d),
+			Snippet:    snippet(d.Content),
+			Score:      d.Score,
+		})
+	}
+	return out
+}
+
+// gather retrieves and labels the documents for one WHY. Grounding is
+// best effort: a failed lookup leaves the answer uncited rather than
+// failing the run.
+func (o *Orchestrator) gather(ctx context.Context, owner types.Owner, query string, set *evidenceSet) []types.Citation {
+	if o.evidence.Source == nil || set == nil {
+		return nil
+	}
+	docs, err := o.evidence.Source.Evidence(ctx, owner, query, o.evidence.Limit)
+	if err != nil {
+		log.Printf("5why: evidence lookup failed: %v", err)
+		return nil
+	}
+	return set.cite(docs)
+}
+
+// groundRootCause resolves the refs in rc's evidence against the
+// documents the steps cited.
+func groundRootCause(rc *types.RootCauseResult, steps []types.FiveWhyStep) {
+	rc.Citations, rc.InvalidCitations = resolveCitations(
+		strings.Join(rc.Evidence, "\n"),
+		types.StepCitations(steps),
+	)
+}
+
+// citationRefs matches "[D1]" and "[D1, D3]".
+var citationRefs = regexp.MustCompile(`\[(D\d+(?:\s*,\s*D\d+)*)\]`)
+
+// resolveCitations checks the refs cited in text against the documents
+// that were shown: known refs become citations, unknown ones are
+// returned as invalid. Both come out once each, in citation order.
+func resolveCitations(text string, shown []types.Citation) ([]types.Citation, []string) {
+	byRef := make(map[string]types.Citation, len(shown))
+	for _, c := range shown {
+		byRef[c.Ref] = c
+	}
+
+	var (
+		valid   []types.Citation
+		invalid []string
+		seen    = make(map[string]bool)
+	)
+	for _, m := range citationRefs.FindAllStringSubmatch(text, -1) {
+		for _, ref := range strings.Split(m[1], ",") {
+			ref = strings.TrimSpace(ref)
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+
+			if c, ok := byRef[ref]; ok {
+				valid = append(valid, c)
+			} else {
+				invalid = append(invalid, ref)
+			}
+		}
+	}
+	return valid, invalid
+}
+
+// documentSource names where a document came from: the ingested file,
+// or the kind of memory.
+func documentSource(d vector.Document) string {
+	if s, ok := d.Meta["source"].(string); ok && s != "" {
+		return s
+	}
+	s, _ := d.Meta["type"].(string)
+	return s
+}
+
+func snippet(content string) string {
+	s := strings.Join(strings.Fields(content), " ")
+	if r := []rune(s); len(r) > maxSnippetLen {
+		s = string(r[:maxSnippetLen]) + "…"
+	}
+	return s
+}
+
+// ================================
+// Memory Evidence
+// ================================
+
+// investigationTypes are the memories past 5-Why runs leave behind.
+var investigationTypes = []string{"question", "root_cause", "solution"}
+
+// Evidence searches owner's past investigations and the knowledge base
+// (ingested runbooks and docs) visible to owner, and keeps the best limit
+// of them.
+// Unlike Recall it does not rerank: it runs once per WHY level.
+func (m *MemoryEngine) Evidence(ctx context.Context, owner types.Owner, query string, limit int) ([]vector.Document, error) {
+	if owner.UserID == "" {
+		return nil, errors.New("evidence requires a user id")
+	}
+	if limit <= 0 {
+		limit = DefaultEvidenceLimit
+	}
+
+	emb, err := m.llm.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	past := recallSearchOptions(RecallOptions{
+		UserID:   owner.UserID,
+		TenantID: owner.TenantID,
+		Types:    investigationTypes,
+		Limit:    limit,
+		MinScore: m.minScore,
+	})
+	searches := []vector.SearchOptions{past}
+	for _, f := range knowledgeFilters(owner) {
+		searches = append(searches, vector.SearchOptions{Limit: limit, MinScore: m.minScore, Filter: f})
+	}
+
+	var docs []vector.Document
+	for _, opts := range searches {
+		found, err := vector.HybridSearch(ctx, m.vector, query, emb, vector.HybridOptions{SearchOptions: opts})
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, found...)
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+	if len(docs) > limit {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+// knowledgeFilters scope the knowledge base to the shared, default
+// namespace and, for a tenant, the namespace named after it.
+func knowledgeFilters(owner types.Owner) []vector.Filter {
+	only := map[string]interface{}{"type": ingest.DocumentType}
+	filters := []vector.Filter{{ExactNamespace: true, Equals: only}}
+	if owner.TenantID != "" {
+		filters = append(filters, vector.Filter{Namespace: owner.TenantID, Equals: only})
+	}
+	return filters
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"testing"
+
+	"quavixAI/internal/modules/ingest"
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+)
+
+func TestKnowledgeFilters(t *testing.T) {
+	doc := func(namespace string) vector.Document {
+		return vector.Document{Namespace: namespace, Meta: map[string]interface{}{"type": ingest.DocumentType}}
+	}
+	visible := func(owner types.Owner, d vector.Document) bool {
+		for _, f := range knowledgeFilters(owner) {
+			if f.Match(d) {
+				return true
+			}
+		}
+		return false
+	}
+
+	tests := []struct {
+		owner     types.Owner
+		namespace string
+		want      bool
+	}{
+		{types.Owner{UserID: "u1"}, "", true},
+		{types.Owner{UserID: "u1"}, "acme", false},
+		{types.Owner{UserID: "u1", TenantID: "acme"}, "", true},
+		{types.Owner{UserID: "u1", TenantID: "acme"}, "acme", true},
+		{types.Owner{UserID: "u1", TenantID: "acme"}, "globex", false},
+	}
+	for _, tt := range tests {
+		if got := visible(tt.owner, doc(tt.namespace)); got != tt.want {
+			t.Errorf("tenant %q sees namespace %q: %v, want %v", tt.owner.TenantID, tt.namespace, got, tt.want)
+		}
+	}
+
+	// investigation memories never come through the knowledge search
+	memory := vector.Document{Meta: map[string]interface{}{"type": "root_cause"}}
+	if visible(types.Owner{UserID: "u1"}, memory) {
+		t.Errorf("knowledge filters matched a %v memory", memory.Meta["type"])
+	}
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/types"
+)
+
+// ================================
+// Fault Tree Analysis
+// ================================
+
+const MethodFaultTree = "fault_tree"
+
+const (
+	DefaultFaultTreeDepth = 3
+	DefaultFaultTreeNodes = 20
+
+	// cut sets are capped so a wide AND over ORs cannot explode
+	maxCutSets = 64
+)
+
+type FaultTreeResult struct {
+	TopEvent string               `json:"top_event"`
+	Tree     *types.FaultTreeNode `json:"tree"`
+
+	// MinimalCutSets are the smallest combinations of basic events that
+	// cause the top event, smallest first.
+	MinimalCutSets [][]string `json:"minimal_cut_sets"`
+}
+
+// Summary names the smallest cut set: the cheapest way for the top
+// event to occur.
+func (r *FaultTreeResult) Summary() string {
+	if len(r.MinimalCutSets) == 0 {
+		return ""
+	}
+	return strings.Join(r.MinimalCutSets[0], " AND ")
+}
+
+// FaultTree decomposes the problem, as the top event, through AND/OR
+// gates down to basic events, then derives the minimal cut sets.
+type FaultTree struct {
+	llm      *llm.Manager
+	prompt   prompt.Builder
+	maxDepth int
+	maxNodes int
+}
+
+func NewFaultTree(cfg MethodConfig) *FaultTree {
+	cfg.defaults()
+	return &FaultTree{
+		llm:      cfg.LLM,
+		prompt:   cfg.Prompt,
+		maxDepth: DefaultFaultTreeDepth,
+		maxNodes: DefaultFaultTreeNodes,
+	}
+}
+
+func (f *FaultTree) Name() string {
+	return MethodFaultTree
+}
+
+func (f *FaultTree) Analyze(ctx context.Context, req AnalysisRequest) (Analysis, error) {
+	budget := &whyBudget{left: f.maxNodes}
+	budget.take() // the top event
+
+	tree, err := f.expand(ctx, req.Problem, req.Problem, 1, budget)
+	if err != nil {
+		return nil, err
+	}
+	if tree.Gate == "" {
+		return nil, errors.New("fault tree could not decompose the top event")
+	}
+
+	return &FaultTreeResult{
+		TopEvent:       req.Problem,
+		Tree:           tree,
+		MinimalCutSets: minimalCutSets(tree),
+	}, nil
+}
+
+// expand decomposes event and its causes in parallel. Events past
+// maxDepth, or once the node budget is spent, stay basic.
+func (f *FaultTree) expand(ctx context.Context, top, event string, depth int, budget *whyBudget) (*types.FaultTreeNode, error) {
+	node := &types.FaultTreeNode{Event: event}
+	if depth > f.maxDepth {
+		return node, nil
+	}
+
+	resp, err := f.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeDiagnosis,
+		Prompt: f.prompt.BuildFaultTreePrompt(top, event, depth),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var gate types.FaultTreeGate
+	if err := f.prompt.ParseFaultTreeGate(resp.Text, &gate); err != nil {
+		return nil, err
+	}
+	if gate.Basic {
+		return node, nil
+	}
+
+	var causes []string
+	for _, c := range gate.Causes {
+		if budget.take() {
+			causes = append(causes, c)
+		}
+	}
+	if len(causes) == 0 {
+		return node, nil
+	}
+	// an AND missing some of its inputs would understate the event
+	if gate.Gate == types.GateAND && len(causes) < len(gate.Causes) {
+		causes = gate.Causes
+	}
+
+	node.Gate = gate.Gate
+	node.Children = make([]*types.FaultTreeNode, len(causes))
+	errs := make([]error, len(causes))
+
+	var wg sync.WaitGroup
+	for i, cause := range causes {
+		wg.Add(1)
+		go func(i int, cause string) {
+			defer wg.Done()
+			node.Children[i], errs[i] = f.expand(ctx, top, cause, depth+1, budget)
+		}(i, cause)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// minimalCutSets expands the tree bottom-up: an OR unions its inputs'
+// cut sets, an AND combines one from each. Every gate drops supersets
+// before capping, so the cap only ever discards the largest sets.
+func minimalCutSets(node *types.FaultTreeNode) [][]string {
+	if node.Gate == "" || len(node.Children) == 0 {
+		return [][]string{{node.Event}}
+	}
+
+	if node.Gate == types.GateOR {
+		var out [][]string
+		for _, child := range node.Children {
+			out = append(out, minimalCutSets(child)...)
+		}
+		return capCutSets(out)
+	}
+
+	out := [][]string{{}}
+	for _, child := range node.Children {
+		sets := minimalCutSets(child)
+		next := make([][]string, 0, len(out)*len(sets))
+		for _, a := range out {
+			for _, b := range sets {
+				next = append(next, union(a, b))
+			}
+		}
+		out = capCutSets(next)
+	}
+	return out
+}
+
+// capCutSets drops duplicates and supersets, then keeps the maxCutSets
+// smallest, smallest first.
+func capCutSets(sets [][]string) [][]string {
+	sort.Slice(sets, func(i, j int) bool {
+		if len(sets[i]) != len(sets[j]) {
+			return len(sets[i]) < len(sets[j])
+		}
+		return strings.Join(sets[i], "\x00") < strings.Join(sets[j], "\x00")
+	})
+
+	var minimal [][]string
+	for _, s := range sets {
+		redundant := false
+		for _, m := range minimal {
+			if subset(m, s) {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			minimal = append(minimal, s)
+			if len(minimal) == maxCutSets {
+				break
+			}
+		}
+	}
+	return minimal
+}
+
+// union merges two sorted event sets.
+func union(a, b []string) []string {
+	out := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			out = append(out, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			out = append(out, b[j])
+			j++
+		default: // equal
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// subset reports whether sorted a is contained in sorted b.
+func subset(a, b []string) bool {
+	j := 0
+	for _, x := range a {
+		for j < len(b) && b[j] < x {
+			j++
+		}
+		if j == len(b) || b[j] != x {
+			return false
+		}
+		j++
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package chat
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"quavixAI/internal/modules/types"
+)
+
+func basic(event string) *types.FaultTreeNode {
+	return &types.FaultTreeNode{Event: event}
+}
+
+func gate(g string, children ...*types.FaultTreeNode) *types.FaultTreeNode {
+	return &types.FaultTreeNode{Event: "gate", Gate: g, Children: children}
+}
+
+func TestMinimalCutSets(t *testing.T) {
+	tests := []struct {
+		name string
+		tree *types.FaultTreeNode
+		want [][]string
+	}{
+		{"basic", basic("a"), [][]string{{"a"}}},
+		{"or", gate(types.GateOR, basic("b"), basic("a"), basic("b")), [][]string{{"a"}, {"b"}}},
+		{"and", gate(types.GateAND, basic("b"), basic("a")), [][]string{{"a", "b"}}},
+		{
+			// (a OR b) AND (a OR c) = a OR (b AND c)
+			"absorption",
+			gate(types.GateAND,
+				gate(types.GateOR, basic("a"), basic("b")),
+				gate(types.GateOR, basic("a"), basic("c")),
+			),
+			[][]string{{"a"}, {"b", "c"}},
+		},
+		{
+			"nested",
+			gate(types.GateOR,
+				gate(types.GateAND, basic("a"), basic("b"), basic("c")),
+				gate(types.GateAND, basic("a"), basic("b")),
+				basic("d"),
+			),
+			[][]string{{"d"}, {"a", "b"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := minimalCutSets(tt.tree); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinimalCutSetsCapKeepsSmallest(t *testing.T) {
+	// the wide AND yields 3^5 = 243 three-to-five event sets; the single
+	// event must survive the cap however the products are ordered
+	var ors []*types.FaultTreeNode
+	for i := 0; i < 5; i++ {
+		ors = append(ors, gate(types.GateOR,
+			basic(fmt.Sprintf("x%d", i)), basic(fmt.Sprintf("y%d", i)), basic(fmt.Sprintf("z%d", i))))
+	}
+	tree := gate(types.GateOR, gate(types.GateAND, ors...), basic("single"))
+
+	got := minimalCutSets(tree)
+	if len(got) != maxCutSets {
+		t.Fatalf("got %d cut sets, want the cap of %d", len(got), maxCutSets)
+	}
+	if !reflect.DeepEqual(got[0], []string{"single"}) {
+		t.Fatalf("smallest cut set %v, want [single]", got[0])
+	}
+	for i := 1; i < len(got); i++ {
+		if len(got[i]) < len(got[i-1]) {
+			t.Fatalf("cut sets not ordered by size: %v before %v", got[i-1], got[i])
+		}
+	}
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct{ a, b, want []string }{
+		{nil, nil, []string{}},
+		{[]string{"a"}, nil, []string{"a"}},
+		{nil, []string{"b"}, []string{"b"}},
+		{[]string{"a", "c"}, []string{"b", "d"}, []string{"a", "b", "c", "d"}},
+		{[]string{"a", "b"}, []string{"b", "c"}, []string{"a", "b", "c"}},
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := union(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("union(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSubset(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want bool
+	}{
+		{nil, nil, true},
+		{nil, []string{"a"}, true},
+		{[]string{"a"}, nil, false},
+		{[]string{"a", "c"}, []string{"a", "b", "c"}, true},
+		{[]string{"a", "b", "c"}, []string{"a", "b", "c"}, true},
+		{[]string{"a", "d"}, []string{"a", "b", "c"}, false},
+		{[]string{"b", "b"}, []string{"b"}, false},
+		{[]string{"a", "b", "c"}, []string{"a", "c"}, false},
+	}
+	for _, tt := range tests {
+		if got := subset(tt.a, tt.b); got != tt.want {
+			t.Errorf("subset(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"errors"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/types"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ================================
+// Fishbone (Ishikawa) Analysis
+// ================================
+
+const MethodFishbone = "fishbone"
+
+// SixM are the classic fishbone categories.
+var SixM = []string{"Man", "Machine", "Method", "Material", "Measurement", "Mother Nature"}
+
+type FishboneResult struct {
+	Problem    string                   `json:"problem"`
+	Categories []types.FishboneCategory `json:"categories"`
+	RootCause  types.RootCauseResult    `json:"root_cause"`
+}
+
+func (r *FishboneResult) Summary() string {
+	return r.RootCause.RootCause
+}
+
+// Fishbone brainstorms causes for every category concurrently, then
+// weighs the whole diagram to pick the root cause.
+type Fishbone struct {
+	llm        *llm.Manager
+	prompt     prompt.Builder
+	categories []string
+	workers    int
+}
+
+// NewFishbone analyses over categories, the 6M when none are given.
+func NewFishbone(cfg MethodConfig, categories ...string) *Fishbone {
+	cfg.defaults()
+	if len(categories) == 0 {
+		categories = SixM
+	}
+	return &Fishbone{
+		llm:        cfg.LLM,
+		prompt:     cfg.Prompt,
+		categories: categories,
+		workers:    cfg.Workers,
+	}
+}
+
+func (f *Fishbone) Name() string {
+	return MethodFishbone
+}
+
+func (f *Fishbone) Analyze(ctx context.Context, req AnalysisRequest) (Analysis, error) {
+	result := &FishboneResult{
+		Problem:    req.Problem,
+		Categories: make([]types.FishboneCategory, len(f.categories)),
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(f.workers)
+	for i, category := range f.categories {
+		g.Go(func() error {
+			resp, err := f.llm.Generate(gctx, llm.Request{
+				Mode:   llm.ModeDiagnosis,
+				Prompt: f.prompt.BuildFishbonePrompt(req.Problem, category),
+			})
+			if err != nil {
+				return err
+			}
+
+			out := &result.Categories[i]
+			if err := f.prompt.ParseFishboneCategory(resp.Text, out); err != nil {
+				return err
+			}
+			out.Category = category // the model may rename it
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	causes := 0
+	for _, c := range result.Categories {
+		causes += len(c.Causes)
+	}
+	if causes == 0 {
+		return nil, errors.New("fishbone analysis found no causes")
+	}
+
+	resp, err := f.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeDiagnosis,
+		Prompt: f.prompt.BuildFishboneSynthesisPrompt(req.Problem, result.Categories),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := f.prompt.ParseRootCause(resp.Text, &result.RootCause); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
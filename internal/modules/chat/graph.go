@@ -0,0 +1,362 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/types"
+)
+
+// ================================
+// Memory Graph Models
+// ================================
+
+type GraphNode struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Kind           string `json:"kind"`
+	Investigations int    `json:"investigations"`
+}
+
+// GraphNeighbor is an entity linked to the queried one, with the number
+// of investigations that stated the link.
+type GraphNeighbor struct {
+	Name           string `json:"name"`
+	Kind           string `json:"kind"`
+	Investigations int    `json:"investigations"`
+}
+
+// EntityGraph is one entity with its direct causes and effects.
+// AsCause / AsEffect count distinct investigations.
+type EntityGraph struct {
+	Entity   GraphNode       `json:"entity"`
+	AsCause  int             `json:"as_cause"`
+	AsEffect int             `json:"as_effect"`
+	Causes   []GraphNeighbor `json:"causes"`  // what leads to the entity
+	Effects  []GraphNeighbor `json:"effects"` // what the entity leads to
+}
+
+var ErrEntityNotFound = errors.New("entity not found")
+
+// ================================
+// Graph Store (Postgres)
+// ================================
+
+// GraphStore keeps entities as nodes keyed by a normalized name, one
+// mention row per (entity, investigation, user) and cause -> effect edges
+// tagged with the investigation that produced them. Mentions and edges
+// carry their owner; queries only see those of the caller's tenant, or
+// the caller's own when they have no tenant.
+type GraphStore struct {
+	db *sql.DB
+}
+
+func NewGraphStore(db *sql.DB) *GraphStore {
+	return &GraphStore{db: db}
+}
+
+func (g *GraphStore) Init(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS memory_graph_nodes (
+			id BIGSERIAL PRIMARY KEY,
+			key TEXT UNIQUE NOT NULL,
+			name TEXT NOT NULL,
+			kind TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS memory_graph_mentions (
+			node_id BIGINT NOT NULL REFERENCES memory_graph_nodes(id) ON DELETE CASCADE,
+			session_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT '',
+			tenant_id TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS memory_graph_edges (
+			id BIGSERIAL PRIMARY KEY,
+			cause_id BIGINT NOT NULL REFERENCES memory_graph_nodes(id) ON DELETE CASCADE,
+			effect_id BIGINT NOT NULL REFERENCES memory_graph_nodes(id) ON DELETE CASCADE,
+			session_id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT '',
+			tenant_id TEXT NOT NULL DEFAULT '',
+			evidence TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS memory_graph_edges_effect_idx ON memory_graph_edges (effect_id);`,
+
+		`ALTER TABLE memory_graph_mentions ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE memory_graph_edges ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';`,
+
+		// session ids are client supplied, so two users may share one;
+		// rows are keyed by user as well (older tables keyed without it)
+		`ALTER TABLE memory_graph_mentions DROP CONSTRAINT IF EXISTS memory_graph_mentions_pkey;`,
+		`ALTER TABLE memory_graph_edges DROP CONSTRAINT IF EXISTS memory_graph_edges_cause_id_effect_id_session_id_key;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS memory_graph_mentions_key ON memory_graph_mentions (node_id, session_id, user_id);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS memory_graph_edges_key ON memory_graph_edges (cause_id, effect_id, session_id, user_id);`,
+		`CREATE INDEX IF NOT EXISTS memory_graph_mentions_owner_idx ON memory_graph_mentions (tenant_id, user_id);`,
+		`CREATE INDEX IF NOT EXISTS memory_graph_edges_owner_idx ON memory_graph_edges (tenant_id, user_id);`,
+	}
+
+	for _, q := range queries {
+		if _, err := g.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save records one investigation's extraction. Re-saving the same
+// session is idempotent.
+func (g *GraphStore) Save(ctx context.Context, sessionID string, owner types.Owner, ex types.GraphExtraction) error {
+	if sessionID == "" {
+		return errors.New("missing session id")
+	}
+
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := map[string]int64{}
+
+	node := func(name, kind string) (int64, error) {
+		key := entityKey(name)
+		if key == "" {
+			return 0, nil
+		}
+		if id, ok := ids[key]; ok {
+			return id, nil
+		}
+
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO memory_graph_nodes (key, name, kind)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (key) DO UPDATE SET
+				kind = CASE WHEN memory_graph_nodes.kind = '' THEN EXCLUDED.kind ELSE memory_graph_nodes.kind END
+			RETURNING id;`, key, strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(kind))).Scan(&id)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO memory_graph_mentions (node_id, session_id, user_id, tenant_id)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (node_id, session_id, user_id) DO NOTHING;`, id, sessionID, owner.UserID, owner.TenantID); err != nil {
+			return 0, err
+		}
+
+		ids[key] = id
+		return id, nil
+	}
+
+	for _, e := range ex.Entities {
+		if _, err := node(e.Name, e.Kind); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range ex.Relations {
+		causeID, err := node(r.Cause, "")
+		if err != nil {
+			return err
+		}
+		effectID, err := node(r.Effect, "")
+		if err != nil {
+			return err
+		}
+		if causeID == 0 || effectID == 0 || causeID == effectID {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO memory_graph_edges
+			(cause_id, effect_id, session_id, user_id, tenant_id, evidence)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (cause_id, effect_id, session_id, user_id) DO NOTHING;`,
+			causeID, effectID, sessionID, owner.UserID, owner.TenantID, r.Evidence); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Entity returns an entity with its direct causes and effects, most
+// frequently stated first.
+func (g *GraphStore) Entity(ctx context.Context, owner types.Owner, name string, limit int) (*EntityGraph, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// $1/$2 are the scope in every query below
+	var out EntityGraph
+	err := g.db.QueryRowContext(ctx, `SELECT n.id, n.name, n.kind,
+			(SELECT COUNT(*) FROM memory_graph_mentions m WHERE m.node_id = n.id AND `+ownerScope("m")+`),
+			(SELECT COUNT(DISTINCT (user_id, session_id)) FROM memory_graph_edges e WHERE e.cause_id = n.id AND `+ownerScope("e")+`),
+			(SELECT COUNT(DISTINCT (user_id, session_id)) FROM memory_graph_edges e WHERE e.effect_id = n.id AND `+ownerScope("e")+`)
+		FROM memory_graph_nodes n
+		WHERE n.key = $3;`, owner.TenantID, owner.UserID, entityKey(name)).Scan(
+		&out.Entity.ID, &out.Entity.Name, &out.Entity.Kind,
+		&out.Entity.Investigations, &out.AsCause, &out.AsEffect,
+	)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && out.Entity.Investigations == 0) {
+		return nil, ErrEntityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := func(self, other string) ([]GraphNeighbor, error) {
+		rows, err := g.db.QueryContext(ctx, `SELECT n.name, n.kind, COUNT(DISTINCT (e.user_id, e.session_id)) AS c
+			FROM memory_graph_edges e
+			JOIN memory_graph_nodes n ON n.id = e.`+other+`
+			WHERE e.`+self+` = $3 AND `+ownerScope("e")+`
+			GROUP BY n.id, n.name, n.kind
+			ORDER BY c DESC, n.name
+			LIMIT $4;`, owner.TenantID, owner.UserID, out.Entity.ID, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var list []GraphNeighbor
+		for rows.Next() {
+			var nb GraphNeighbor
+			if err := rows.Scan(&nb.Name, &nb.Kind, &nb.Investigations); err != nil {
+				return nil, err
+			}
+			list = append(list, nb)
+		}
+		return list, rows.Err()
+	}
+
+	if out.Causes, err = neighbors("effect_id", "cause_id"); err != nil {
+		return nil, err
+	}
+	if out.Effects, err = neighbors("cause_id", "effect_id"); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Top lists the entities that appear in the most investigations.
+func (g *GraphStore) Top(ctx context.Context, owner types.Owner, limit int) ([]GraphNode, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := g.db.QueryContext(ctx, `SELECT n.id, n.name, n.kind, COUNT(m.session_id) AS c
+		FROM memory_graph_nodes n
+		JOIN memory_graph_mentions m ON m.node_id = n.id
+		WHERE `+ownerScope("m")+`
+		GROUP BY n.id, n.name, n.kind
+		ORDER BY c DESC, n.name
+		LIMIT $3;`, owner.TenantID, owner.UserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []GraphNode
+	for rows.Next() {
+		var n GraphNode
+		if err := rows.Scan(&n.ID, &n.Name, &n.Kind, &n.Investigations); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// Purge deletes userID's mentions and edges, then any node nobody
+// mentions any more. It returns the number of rows removed.
+func (g *GraphStore) Purge(ctx context.Context, userID string) (int64, error) {
+	tx, err := g.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var total int64
+	for _, q := range []string{
+		`DELETE FROM memory_graph_edges WHERE user_id = $1;`,
+		`DELETE FROM memory_graph_mentions WHERE user_id = $1;`,
+	} {
+		res, err := tx.ExecContext(ctx, q, userID)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM memory_graph_nodes n
+		WHERE NOT EXISTS (SELECT 1 FROM memory_graph_mentions m WHERE m.node_id = n.id)
+		AND NOT EXISTS (SELECT 1 FROM memory_graph_edges e WHERE e.cause_id = n.id OR e.effect_id = n.id);`)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	total += n
+
+	return total, tx.Commit()
+}
+
+// ================================
+// Extraction
+// ================================
+
+// ExtractGraph asks the model for the entities and cause -> effect
+// relations in a finished 5-Why investigation and records them.
+func (m *MemoryEngine) ExtractGraph(ctx context.Context, owner types.Owner, question string, session *FiveWhySession) error {
+	if m.graph == nil {
+		return errors.New("memory graph not configured")
+	}
+	if session == nil {
+		return errors.New("nil session")
+	}
+
+	resp, err := m.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: m.prompt.BuildGraphExtractionPrompt(question, session.Steps, session.RootCause),
+	})
+	if err != nil {
+		return err
+	}
+
+	var ex types.GraphExtraction
+	if err := m.prompt.ParseGraphExtraction(resp.Text, &ex); err != nil {
+		return err
+	}
+
+	return m.graph.Save(ctx, session.SessionID, owner, ex)
+}
+
+func (m *MemoryEngine) Graph() *GraphStore {
+	return m.graph
+}
+
+// ================================
+// Helpers
+// ================================
+
+// ownerScope matches rows of alias visible to the owner passed as $1
+// (tenant) and $2 (user).
+func ownerScope(alias string) string {
+	return "(" + alias + ".tenant_id = $1 AND ($1 <> '' OR " + alias + ".user_id = $2))"
+}
+
+// entityKey folds case, punctuation and spacing so "Deploy-Pipeline"
+// and "deploy pipeline" are the same node.
+func entityKey(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
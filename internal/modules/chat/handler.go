@@ -1,7 +1,10 @@
 package chat
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"quavixAI/internal/modules/types"
@@ -14,10 +17,11 @@ import (
 
 type Handler struct {
 	service *Service
+	jobs    *JobQueue // nil disables ?async=true
 }
 
-func NewHandler(s *Service) *Handler {
-	return &Handler{service: s}
+func NewHandler(s *Service, jobs *JobQueue) *Handler {
+	return &Handler{service: s, jobs: jobs}
 }
 
 // ================================
@@ -30,8 +34,19 @@ type ChatRequest struct {
 }
 
 type FiveWhyRequest struct {
+	SessionID   string `json:"session_id"`
+	Question    string `json:"question"`
+	CallbackURL string `json:"callback_url"` // async only
+	Reuse       bool   `json:"reuse"`        // sync only, see FiveWhyOptions
+}
+
+type AnalysisRequestBody struct {
 	SessionID string `json:"session_id"`
-	Question  string `json:"question"`
+	Problem   string `json:"problem"`
+}
+
+type FiveWhyAnswerRequest struct {
+	Answer string `json:"answer"`
 }
 
 type RootCauseRequest struct {
@@ -48,8 +63,13 @@ type CompressRequest struct {
 }
 
 type RecallRequest struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit"`
+	Query          string   `json:"query"`
+	Limit          int      `json:"limit"`
+	Types          []string `json:"types"`
+	MinScore       float64  `json:"min_score"`
+	SemanticWeight float64  `json:"semantic_weight"`
+	LexicalWeight  float64  `json:"lexical_weight"`
+	Rerank         bool     `json:"rerank"`
 }
 
 // ================================
@@ -62,9 +82,7 @@ func (h *Handler) Chat(c response.Context) error {
 		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
 	}
 
-	userID := c.GetString("user_id")
-
-	resp, err := h.service.Chat(c.Context(), req.SessionID, userID, req.Message)
+	resp, err := h.service.Chat(c.Context(), req.SessionID, ownerOf(c), req.Message)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
 	}
@@ -80,22 +98,172 @@ func (h *Handler) Chat(c response.Context) error {
 // 5-Why Endpoint
 // ================================
 
+// FiveWhy runs the pipeline in the request, or with ?async=true queues
+// it and answers 202 with a job to poll at GET /jobs/{id}.
 func (h *Handler) FiveWhy(c response.Context) error {
 	var req FiveWhyRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
 	}
 
-	userID := c.GetString("user_id")
+	async := false
+	if v := c.Request.URL.Query().Get("async"); v != "" {
+		var err error
+		if async, err = strconv.ParseBool(v); err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error("invalid async"))
+		}
+	}
+	if async {
+		return h.enqueueFiveWhy(c, req)
+	}
 
-	session, err := h.service.FiveWhy(c.Context(), req.SessionID, userID, req.Question)
+	session, err := h.service.FiveWhy(c.Context(), req.SessionID, ownerOf(c), req.Question, FiveWhyOptions{Reuse: req.Reuse})
 	if err != nil {
+		return c.JSON(http.StatusInternalServerError, runError(err))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(session))
+}
+
+func (h *Handler) enqueueFiveWhy(c response.Context, req FiveWhyRequest) error {
+	if h.jobs == nil {
+		return c.JSON(http.StatusServiceUnavailable, response.Error("async jobs are not enabled"))
+	}
+
+	job, err := h.jobs.EnqueueFiveWhy(c.Context(), req.SessionID, ownerOf(c), req.Question, req.CallbackURL)
+	switch {
+	case errors.Is(err, ErrJobQuestion), errors.Is(err, ErrInvalidCallback), errors.Is(err, ErrPrivateCallback):
+		return c.JSON(http.StatusBadRequest, response.Error(err.Error()))
+	case errors.Is(err, ErrWebhooksDisabled):
+		return c.JSON(http.StatusServiceUnavailable, response.Error(err.Error()))
+	case err != nil:
 		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
 	}
 
+	return c.JSON(http.StatusAccepted, response.Success(job))
+}
+
+// SimilarIncidents previews the earlier investigations a 5-Why of ?q=
+// would be matched with, so a client can decide to reuse one.
+func (h *Handler) SimilarIncidents(c response.Context) error {
+	question := strings.TrimSpace(c.Request.URL.Query().Get("q"))
+	if question == "" {
+		return c.JSON(http.StatusBadRequest, response.Error("missing q"))
+	}
+
+	incidents, err := h.service.SimilarIncidents(c.Context(), ownerOf(c), question)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(map[string]interface{}{
+		"incidents": incidents,
+	}))
+}
+
+func (h *Handler) GetJob(c response.Context) error {
+	if h.jobs == nil {
+		return c.JSON(http.StatusNotFound, response.Error(ErrJobNotFound.Error()))
+	}
+
+	job, err := h.jobs.Get(c.Context(), ownerOf(c), c.Request.PathValue("id"))
+	if errors.Is(err, ErrJobNotFound) {
+		return c.JSON(http.StatusNotFound, response.Error(err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(job))
+}
+
+func (h *Handler) ResumeFiveWhy(c response.Context) error {
+	session, err := h.service.ResumeFiveWhy(c.Context(), ownerOf(c), c.Request.PathValue("id"))
+	if err != nil {
+		return c.JSON(runStatus(err), runError(err))
+	}
+
 	return c.JSON(http.StatusOK, response.Success(session))
 }
 
+// ================================
+// Interactive 5-Why Endpoints
+// ================================
+
+func (h *Handler) StartFiveWhy(c response.Context) error {
+	var req FiveWhyRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+
+	run, err := h.service.StartFiveWhy(c.Context(), req.SessionID, ownerOf(c), req.Question)
+	if err != nil {
+		return c.JSON(runStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, response.Success(run))
+}
+
+func (h *Handler) AnswerFiveWhy(c response.Context) error {
+	var req FiveWhyAnswerRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+
+	run, err := h.service.AnswerFiveWhy(c.Context(), ownerOf(c), c.Request.PathValue("id"), req.Answer)
+	if err != nil {
+		return c.JSON(runStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(run))
+}
+
+func (h *Handler) FinalizeFiveWhy(c response.Context) error {
+	run, err := h.service.FinalizeFiveWhy(c.Context(), ownerOf(c), c.Request.PathValue("id"))
+	if err != nil {
+		return c.JSON(runStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(run))
+}
+
+func (h *Handler) GetFiveWhyRun(c response.Context) error {
+	run, err := h.service.GetFiveWhyRun(c.Context(), ownerOf(c), c.Request.PathValue("id"))
+	if err != nil {
+		return c.JSON(runStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(run))
+}
+
+// ================================
+// Analysis Endpoints
+// ================================
+
+// Analyze serves POST /analysis/{method} for any registered methodology.
+func (h *Handler) Analyze(c response.Context) error {
+	var req AnalysisRequestBody
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+
+	report, err := h.service.Analyze(c.Context(), c.Request.PathValue("method"), req.SessionID, ownerOf(c), req.Problem)
+	if errors.Is(err, ErrUnknownMethod) {
+		return c.JSON(http.StatusNotFound, response.Error(err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(report))
+}
+
+func (h *Handler) Methods(c response.Context) error {
+	return c.JSON(http.StatusOK, response.Success(map[string]interface{}{
+		"methods": h.service.Methods(),
+	}))
+}
+
 // ================================
 // Root Cause Endpoint
 // ================================
@@ -142,7 +310,10 @@ func (h *Handler) CompressSession(c response.Context) error {
 		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
 	}
 
-	summary, err := h.service.CompressSession(c.Context(), req.SessionID)
+	summary, err := h.service.CompressSession(c.Context(), ownerOf(c), req.SessionID)
+	if errors.Is(err, ErrSessionNotFound) {
+		return c.JSON(http.StatusNotFound, response.Error(err.Error()))
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
 	}
@@ -153,16 +324,120 @@ func (h *Handler) CompressSession(c response.Context) error {
 	}))
 }
 
+// GetSession pages through session memory: ?limit=N returns the newest
+// N messages and ?offset=K skips the newest K first.
+func (h *Handler) GetSession(c response.Context) error {
+	q := c.Request.URL.Query()
+
+	var page SessionPage
+	var err error
+	if v := q.Get("offset"); v != "" {
+		if page.Offset, err = strconv.Atoi(v); err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error("invalid offset"))
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if page.Limit, err = strconv.Atoi(v); err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error("invalid limit"))
+		}
+	}
+
+	session, err := h.service.GetSession(c.Context(), ownerOf(c), c.Request.PathValue("id"), page)
+	if errors.Is(err, ErrSessionNotFound) {
+		return c.JSON(http.StatusNotFound, response.Error(err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(session))
+}
+
+// Graph serves GET /memory/graph?entity=&limit=. Without an entity it
+// lists the entities that appear in the most investigations.
+func (h *Handler) Graph(c response.Context) error {
+	q := c.Request.URL.Query()
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, response.Error("invalid limit"))
+		}
+		limit = n
+	}
+
+	graph, err := h.service.EntityGraph(c.Context(), ownerOf(c), q.Get("entity"), limit)
+	if errors.Is(err, ErrEntityNotFound) {
+		return c.JSON(http.StatusNotFound, response.Error(err.Error()))
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(graph))
+}
+
 func (h *Handler) Recall(c response.Context) error {
 	var req RecallRequest
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
 	}
 
-	mem, err := h.service.Recall(c.Context(), req.Query, req.Limit)
+	owner := ownerOf(c)
+	mem, err := h.service.Recall(c.Context(), req.Query, RecallOptions{
+		UserID:   owner.UserID,
+		TenantID: owner.TenantID,
+		Types:    req.Types,
+		Limit:    req.Limit,
+		MinScore: req.MinScore,
+
+		SemanticWeight: req.SemanticWeight,
+		LexicalWeight:  req.LexicalWeight,
+		Rerank:         req.Rerank,
+	})
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
 	}
 
 	return c.JSON(http.StatusOK, response.Success(mem))
 }
+
+// ================================
+// Helpers
+// ================================
+
+// ownerOf reads the caller set by middleware.JWTAuthMiddleware.
+func ownerOf(c response.Context) types.Owner {
+	return types.Owner{
+		UserID:   c.GetString("userID"),
+		TenantID: c.GetString("tenantID"),
+	}
+}
+
+// runError adds the run to resume and the stage that failed to the
+// error body of a checkpointed run.
+func runError(err error) map[string]interface{} {
+	body := response.Error(err.Error())
+
+	var rerr *RunError
+	if errors.As(err, &rerr) {
+		body["run_id"] = rerr.RunID
+		body["failed_stage"] = rerr.Stage
+	}
+	return body
+}
+
+func runStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrRunNotFound), errors.Is(err, ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrRunConflict), errors.Is(err, ErrRunNotAwaiting), errors.Is(err, ErrRunFinalized),
+		errors.Is(err, ErrRunInProgress), errors.Is(err, ErrRunNotResumable), errors.Is(err, ErrRunNotInteractive):
+		return http.StatusConflict
+	case errors.Is(err, ErrRunNotAnswered):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,164 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/vector"
+)
+
+// Metadata keys written on every long-term memory.
+const (
+	MetaImportance   = "importance"
+	MetaLastAccessed = "last_accessed"
+)
+
+// ================================
+// Importance Scoring
+// ================================
+
+// ImportanceScorer rates how worth keeping a memory is, in [0,1].
+type ImportanceScorer interface {
+	Score(ctx context.Context, content string, meta map[string]interface{}) (float64, error)
+}
+
+// RuleScorer assigns importance from Meta["type"].
+type RuleScorer struct {
+	ByType  map[string]float64
+	Default float64
+}
+
+func NewRuleScorer() *RuleScorer {
+	return &RuleScorer{
+		ByType: map[string]float64{
+			"root_cause":           0.9,
+			"solution":             0.7,
+			"session_summary":      0.6,
+			"knowledge":            0.6,
+			"question":             0.5,
+			llm.MemoryTypeResponse: 0.2,
+		},
+		Default: 0.3,
+	}
+}
+
+func (r *RuleScorer) Score(ctx context.Context, content string, meta map[string]interface{}) (float64, error) {
+	if t, ok := meta["type"].(string); ok {
+		if v, ok := r.ByType[t]; ok {
+			return v, nil
+		}
+	}
+	return r.Default, nil
+}
+
+// LLMScorer asks the model for a 1-10 rating and maps it onto [0,1].
+// Any failure other than cancellation falls back to the rules.
+type LLMScorer struct {
+	llm      *llm.Manager
+	prompt   prompt.Builder
+	fallback ImportanceScorer
+}
+
+func NewLLMScorer(llmMgr *llm.Manager, pb prompt.Builder, fallback ImportanceScorer) *LLMScorer {
+	if fallback == nil {
+		fallback = NewRuleScorer()
+	}
+	return &LLMScorer{
+		llm:      llmMgr,
+		prompt:   pb,
+		fallback: fallback,
+	}
+}
+
+func (s *LLMScorer) Score(ctx context.Context, content string, meta map[string]interface{}) (float64, error) {
+	if s.llm == nil {
+		return s.fallback.Score(ctx, content, meta)
+	}
+
+	memType, _ := meta["type"].(string)
+	resp, err := s.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: s.prompt.BuildImportancePrompt(memType, content),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, err
+		}
+		return s.fallback.Score(ctx, content, meta)
+	}
+
+	rating, err := s.prompt.ParseImportanceScore(resp.Text)
+	if err != nil {
+		return s.fallback.Score(ctx, content, meta)
+	}
+
+	return (rating - 1) / 9, nil
+}
+
+// ================================
+// Recall Ranking
+// ================================
+
+// DefaultRecencyHalfLife is how long after its last access a memory's
+// recency score halves.
+const DefaultRecencyHalfLife = 7 * 24 * time.Hour
+
+// RecallWeights blends the three generative-agents signals. Relevance
+// is the reranker score, importance comes from Meta (or the rules when
+// a memory predates scoring) and recency decays exponentially from the
+// last access. All zero means equal weights.
+type RecallWeights struct {
+	Relevance  float64
+	Importance float64
+	Recency    float64
+	HalfLife   time.Duration
+}
+
+func (w *RecallWeights) defaults() {
+	if w.Relevance == 0 && w.Importance == 0 && w.Recency == 0 {
+		w.Relevance, w.Importance, w.Recency = 1, 1, 1
+	}
+	if w.HalfLife <= 0 {
+		w.HalfLife = DefaultRecencyHalfLife
+	}
+}
+
+// rankMemories replaces each Score with the weighted blend, normalized
+// back to [0,1], and sorts best first.
+func rankMemories(ctx context.Context, docs []vector.Document, w RecallWeights, rules ImportanceScorer, now time.Time) []vector.Document {
+	total := w.Relevance + w.Importance + w.Recency
+
+	for i := range docs {
+		d := &docs[i]
+
+		importance, ok := vector.MetaNumber(d.Meta[MetaImportance])
+		if !ok {
+			importance, _ = rules.Score(ctx, d.Content, d.Meta)
+		}
+
+		age := now.Sub(lastAccessed(*d)).Hours()
+		if age < 0 {
+			age = 0
+		}
+		recency := math.Pow(0.5, age/w.HalfLife.Hours())
+
+		d.Score = (w.Relevance*d.Score + w.Importance*importance + w.Recency*recency) / total
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Score > docs[j].Score })
+	return docs
+}
+
+func lastAccessed(d vector.Document) time.Time {
+	if s, ok := d.Meta[MetaLastAccessed].(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t
+		}
+	}
+	return d.CreatedAt
+}
@@ -0,0 +1,253 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"quavixAI/internal/modules/types"
+
+	"github.com/google/uuid"
+)
+
+// ================================
+// Interactive 5-Why Runs
+// ================================
+
+type RunMode string
+
+const (
+	RunInteractive RunMode = "interactive"
+	RunAuto        RunMode = "auto" // POST /chat/5why, checkpointed per stage
+)
+
+type RunStatus string
+
+const (
+	RunAwaitingAnswer RunStatus = "awaiting_answer"
+	RunReady          RunStatus = "ready" // root cause reached or depth cap hit
+	RunFinalized      RunStatus = "finalized"
+	RunRunning        RunStatus = "running"
+	RunFailed         RunStatus = "failed"
+)
+
+// FiveWhyRun is a 5-Why investigation stored in Postgres. Interactive
+// runs are answered by a human one WHY at a time and can be continued
+// from any device; auto runs hold the pipeline's partial result and the
+// stages completed so far. Version guards against concurrent writers.
+type FiveWhyRun struct {
+	ID              string              `json:"id"`
+	SessionID       string              `json:"session_id"`
+	UserID          string              `json:"user_id"`
+	TenantID        string              `json:"tenant_id,omitempty"`
+	Question        string              `json:"question"`
+	Mode            RunMode             `json:"mode"`
+	Status          RunStatus           `json:"status"`
+	Level           int                 `json:"level"`
+	CurrentQuestion string              `json:"why_question,omitempty"`
+	Steps           []types.FiveWhyStep `json:"steps"`
+	Result          *FiveWhySession     `json:"result,omitempty"`
+	CompletedStages []string            `json:"completed_stages,omitempty"`
+	FailedStage     string              `json:"failed_stage,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	Version         int                 `json:"version"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+var (
+	ErrRunNotFound       = errors.New("5-why run not found")
+	ErrRunConflict       = errors.New("5-why run was updated concurrently, reload and retry")
+	ErrRunNotAwaiting    = errors.New("5-why run is not waiting for an answer")
+	ErrRunFinalized      = errors.New("5-why run is already finalized")
+	ErrRunNotAnswered    = errors.New("5-why run has no answers yet")
+	ErrRunInProgress     = errors.New("5-why run is still in progress")
+	ErrRunNotResumable   = errors.New("only automatic 5-why runs can be resumed")
+	ErrRunNotInteractive = errors.New("5-why run is not interactive")
+	errRunsUnavailable   = errors.New("5-why runs require a repository")
+)
+
+// StartFiveWhy opens a run and returns it with WHY #1. Without a
+// session ID the run ID doubles as the session.
+func (s *Service) StartFiveWhy(ctx context.Context, sessionID string, owner types.Owner, question string) (*FiveWhyRun, error) {
+	if !s.cfg.FiveWhy {
+		return nil, errors.New("five-why engine disabled")
+	}
+	if s.repo == nil {
+		return nil, errRunsUnavailable
+	}
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, errors.New("empty question")
+	}
+	ctx = types.WithOwner(ctx, owner)
+
+	now := time.Now()
+	run := &FiveWhyRun{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    owner.UserID,
+		TenantID:  owner.TenantID,
+		Question:  question,
+		Mode:      RunInteractive,
+		Status:    RunAwaitingAnswer,
+		Level:     1,
+		Steps:     []types.FiveWhyStep{},
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if run.SessionID == "" {
+		run.SessionID = run.ID
+	}
+
+	why, err := s.orchestrator.AskWhy(ctx, 1, question)
+	if err != nil {
+		return nil, err
+	}
+	run.CurrentQuestion = why
+
+	if err := s.appendRun(ctx, owner, run.SessionID, "user", question); err != nil {
+		return nil, err
+	}
+	if err := s.appendRun(ctx, owner, run.SessionID, "assistant", why); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateFiveWhyRun(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// AnswerFiveWhy evaluates the human answer to the pending WHY and asks
+// the next one, or marks the run ready once the verdict calls it the
+// root cause or the depth cap is reached.
+func (s *Service) AnswerFiveWhy(ctx context.Context, owner types.Owner, id, answer string) (*FiveWhyRun, error) {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return nil, errors.New("empty answer")
+	}
+
+	run, err := s.GetFiveWhyRun(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Mode == RunAuto {
+		return nil, ErrRunNotInteractive
+	}
+	if run.Status != RunAwaitingAnswer {
+		return nil, ErrRunNotAwaiting
+	}
+	ctx = types.WithOwner(ctx, owner)
+
+	verdict, err := s.orchestrator.EvaluateAnswer(ctx, run.CurrentQuestion, answer)
+	if err != nil {
+		return nil, err
+	}
+
+	run.Steps = append(run.Steps, types.FiveWhyStep{
+		Level:       run.Level,
+		Question:    run.CurrentQuestion,
+		Answer:      answer,
+		Analysis:    verdict.Analysis,
+		IsRootCause: verdict.IsRootCause,
+		DepthScore:  verdict.DepthScore,
+	})
+
+	next := ""
+	if !s.orchestrator.stop(run.Level, verdict) {
+		if next, err = s.orchestrator.NextWhy(ctx, answer); err != nil {
+			return nil, err
+		}
+		run.Level++
+	} else {
+		run.Status = RunReady
+	}
+	run.CurrentQuestion = next
+	run.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateFiveWhyRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if err := s.appendRun(ctx, owner, run.SessionID, "user", answer); err != nil {
+		return nil, err
+	}
+	if next != "" {
+		if err := s.appendRun(ctx, owner, run.SessionID, "assistant", next); err != nil {
+			return nil, err
+		}
+	}
+
+	return run, nil
+}
+
+// FinalizeFiveWhy runs root cause extraction, solution synthesis and
+// reframing over the answered steps. It can be called before every WHY
+// is answered.
+func (s *Service) FinalizeFiveWhy(ctx context.Context, owner types.Owner, id string) (*FiveWhyRun, error) {
+	run, err := s.GetFiveWhyRun(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Mode == RunAuto {
+		return nil, ErrRunNotInteractive
+	}
+	if run.Status == RunFinalized {
+		return nil, ErrRunFinalized
+	}
+	if len(run.Steps) == 0 {
+		return nil, ErrRunNotAnswered
+	}
+	ctx = types.WithOwner(ctx, owner)
+
+	session := &FiveWhySession{
+		SessionID: run.SessionID,
+		RunID:     run.ID,
+		Steps:     run.Steps,
+		CreatedAt: run.CreatedAt,
+	}
+	if err := s.orchestrator.Conclude(ctx, owner, run.Question, session); err != nil {
+		return nil, err
+	}
+
+	run.Result = session
+	run.Status = RunFinalized
+	run.CurrentQuestion = ""
+	run.UpdatedAt = time.Now()
+
+	if err := s.repo.UpdateFiveWhyRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	if err := s.finishFiveWhy(ctx, owner, run.Question, session); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// GetFiveWhyRun loads a run owned by owner; other users' runs look
+// missing.
+func (s *Service) GetFiveWhyRun(ctx context.Context, owner types.Owner, id string) (*FiveWhyRun, error) {
+	if s.repo == nil {
+		return nil, errRunsUnavailable
+	}
+
+	run, err := s.repo.GetFiveWhyRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.UserID != owner.UserID {
+		return nil, ErrRunNotFound
+	}
+	return run, nil
+}
+
+func (s *Service) appendRun(ctx context.Context, owner types.Owner, sessionID, role, content string) error {
+	if s.memory == nil {
+		return nil
+	}
+	return s.memory.AppendSession(ctx, owner, sessionID, role, content)
+}
@@ -0,0 +1,592 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"quavixAI/internal/db"
+	"quavixAI/internal/modules/types"
+
+	"github.com/google/uuid"
+)
+
+// ================================
+// Asynchronous 5-Why Jobs
+// ================================
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+)
+
+const (
+	DefaultJobWorkers      = 4
+	DefaultJobTimeout      = 10 * time.Minute
+	DefaultJobTTL          = 24 * time.Hour
+	DefaultWebhookAttempts = 3
+	DefaultWebhookTimeout  = 10 * time.Second
+
+	jobQueueKey     = "jobs:5why:queue"
+	jobInstancesKey = "jobs:5why:instances"
+
+	// workers wake up this often to notice shutdown
+	jobPollInterval = 5 * time.Second
+
+	// an instance whose lease is not renewed for this long is presumed
+	// dead and the jobs it held are queued again
+	jobLeaseTTL = 30 * time.Second
+)
+
+// Webhook request headers. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" (see SignWebhook).
+const (
+	WebhookIDHeader        = "X-Webhook-ID"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+func jobKey(id string) string {
+	return "job:" + id
+}
+
+// Every instance moves the jobs it runs onto its own processing list and
+// keeps a lease alive while it is up.
+func jobProcessingKey(instance string) string {
+	return "jobs:5why:processing:" + instance
+}
+
+func jobLeaseKey(instance string) string {
+	return "jobs:5why:lease:" + instance
+}
+
+// WebhookDelivery reports the callback of a job.
+type WebhookDelivery struct {
+	URL         string     `json:"url"`
+	Attempts    int        `json:"attempts"`
+	StatusCode  int        `json:"status_code,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// FiveWhyJob is a queued 5-Why run. It lives in Redis for the queue's
+// TTL; RunID points at the checkpointed run, which outlives it and can
+// be resumed if the job failed.
+type FiveWhyJob struct {
+	ID         string           `json:"id"`
+	UserID     string           `json:"user_id"`
+	TenantID   string           `json:"tenant_id,omitempty"`
+	SessionID  string           `json:"session_id"`
+	Question   string           `json:"question"`
+	Status     JobStatus        `json:"status"`
+	RunID      string           `json:"run_id,omitempty"`
+	Result     *FiveWhySession  `json:"result,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Webhook    *WebhookDelivery `json:"webhook,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+// WebhookPayload is the body POSTed to a job's callback URL.
+type WebhookPayload struct {
+	JobID  string          `json:"job_id"`
+	Status JobStatus       `json:"status"`
+	RunID  string          `json:"run_id,omitempty"`
+	Result *FiveWhySession `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobQuestion      = errors.New("empty question")
+	ErrInvalidCallback  = errors.New("callback_url must be an absolute http(s) URL")
+	ErrPrivateCallback  = errors.New("callback_url must resolve to a public address")
+	ErrWebhooksDisabled = errors.New("webhooks are not configured")
+)
+
+type JobConfig struct {
+	Redis   *db.RedisClient
+	Service *Service
+
+	Workers int
+	Timeout time.Duration // per job
+	TTL     time.Duration // how long job records stay readable
+
+	WebhookSecret   string // signs callbacks; empty disables them
+	WebhookAttempts int
+
+	// HTTPClient posts callbacks. The default one refuses to connect to
+	// loopback, private and link-local addresses.
+	HTTPClient *http.Client
+}
+
+func (c *JobConfig) defaults() {
+	if c.Workers <= 0 {
+		c.Workers = DefaultJobWorkers
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultJobTimeout
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultJobTTL
+	}
+	if c.WebhookAttempts <= 0 {
+		c.WebhookAttempts = DefaultWebhookAttempts
+	}
+	if c.HTTPClient == nil {
+		// checked at dial time, after DNS, so a rebinding name cannot
+		// point a callback at an internal service
+		dialer := &net.Dialer{
+			Timeout: DefaultWebhookTimeout,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if !publicIP(net.ParseIP(host)) {
+					return ErrPrivateCallback
+				}
+				return nil
+			},
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil // a proxy would dial on our behalf
+		transport.DialContext = dialer.DialContext
+
+		c.HTTPClient = &http.Client{
+			Timeout:   DefaultWebhookTimeout,
+			Transport: transport,
+			// a redirect would turn the POST into a GET to another host
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+}
+
+// JobQueue runs 5-Why pipelines off the request path on a pool of
+// workers fed by a Redis list, so any instance can pick up a job.
+type JobQueue struct {
+	redis    *db.RedisClient
+	service  *Service
+	cfg      JobConfig
+	instance string // names this queue's processing list and lease
+}
+
+func NewJobQueue(cfg JobConfig) *JobQueue {
+	cfg.defaults()
+	return &JobQueue{
+		redis:    cfg.Redis,
+		service:  cfg.Service,
+		cfg:      cfg,
+		instance: uuid.NewString(),
+	}
+}
+
+// EnqueueFiveWhy queues a 5-Why run for owner. A non-empty callbackURL
+// receives a signed WebhookPayload once the job finishes.
+func (q *JobQueue) EnqueueFiveWhy(ctx context.Context, sessionID string, owner types.Owner, question, callbackURL string) (*FiveWhyJob, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return nil, ErrJobQuestion
+	}
+
+	job := &FiveWhyJob{
+		ID:        uuid.NewString(),
+		UserID:    owner.UserID,
+		TenantID:  owner.TenantID,
+		SessionID: sessionID,
+		Question:  question,
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+	}
+	if job.SessionID == "" {
+		job.SessionID = job.ID
+	}
+
+	if callbackURL != "" {
+		if q.cfg.WebhookSecret == "" {
+			return nil, ErrWebhooksDisabled
+		}
+		if err := validateCallback(ctx, callbackURL); err != nil {
+			return nil, err
+		}
+		job.Webhook = &WebhookDelivery{URL: callbackURL}
+	}
+
+	if err := q.save(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := q.redis.Enqueue(ctx, jobQueueKey, job.ID); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Get loads a job owned by owner; other users' jobs look missing.
+func (q *JobQueue) Get(ctx context.Context, owner types.Owner, id string) (*FiveWhyJob, error) {
+	job, err := q.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.UserID != owner.UserID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Start launches the workers until ctx is cancelled. While they run the
+// instance renews its lease and queues again the jobs of instances whose
+// lease expired, so jobs cut short by a crash or a shutdown are picked
+// up by whichever instance is still alive.
+func (q *JobQueue) Start(ctx context.Context) {
+	if err := q.register(ctx); err != nil {
+		log.Printf("jobs: register failed: %v", err)
+	}
+	q.reap(ctx)
+
+	go q.heartbeat(ctx)
+	for i := 0; i < q.cfg.Workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// register takes out the instance's lease and lists it among those whose
+// lease is checked.
+func (q *JobQueue) register(ctx context.Context) error {
+	if err := q.redis.Set(ctx, jobLeaseKey(q.instance), time.Now().Unix(), jobLeaseTTL); err != nil {
+		return err
+	}
+	return q.redis.AddToSet(ctx, jobInstancesKey, 0, q.instance)
+}
+
+func (q *JobQueue) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(jobLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := q.register(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("jobs: renew lease failed: %v", err)
+		}
+		q.reap(ctx)
+	}
+}
+
+// reap queues again the jobs held by instances whose lease expired.
+// Entries move one at a time, so instances reaping together never queue
+// a job twice.
+func (q *JobQueue) reap(ctx context.Context) {
+	instances, err := q.redis.SetMembers(ctx, jobInstancesKey)
+	if err != nil {
+		log.Printf("jobs: list instances failed: %v", err)
+		return
+	}
+
+	for _, instance := range instances {
+		if instance == q.instance {
+			continue
+		}
+		_, err := q.redis.Get(ctx, jobLeaseKey(instance))
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, db.ErrNil) {
+			log.Printf("jobs: check lease of %s failed: %v", instance, err)
+			continue
+		}
+
+		n, err := q.redis.Requeue(ctx, jobProcessingKey(instance), jobQueueKey)
+		if err != nil {
+			log.Printf("jobs: requeue from %s failed: %v", instance, err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("jobs: requeued %d jobs interrupted on %s", n, instance)
+		}
+		if err := q.redis.RemoveFromSet(ctx, jobInstancesKey, instance); err != nil {
+			log.Printf("jobs: forget %s failed: %v", instance, err)
+		}
+	}
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	processing := jobProcessingKey(q.instance)
+	for ctx.Err() == nil {
+		id, err := q.redis.Dequeue(ctx, jobQueueKey, processing, jobPollInterval)
+		if errors.Is(err, db.ErrNil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("jobs: dequeue failed: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// a job cut short (e.g. by shutdown) stays in processing until
+		// another instance sees the lease expire and requeues it
+		if !q.process(ctx, id) {
+			continue
+		}
+		if err := q.redis.Ack(ctx, processing, id); err != nil {
+			log.Printf("jobs: ack %s failed: %v", id, err)
+		}
+	}
+}
+
+// process runs one job and reports whether it can be acked.
+func (q *JobQueue) process(ctx context.Context, id string) bool {
+	job, err := q.load(ctx, id)
+	if errors.Is(err, ErrJobNotFound) {
+		return true // expired
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		// try again later rather than drop it
+		log.Printf("jobs: load %s failed: %v", id, err)
+		if err := q.redis.Enqueue(ctx, jobQueueKey, id); err != nil {
+			log.Printf("jobs: requeue %s failed: %v", id, err)
+			return false
+		}
+		return true
+	}
+	if job.Status == JobCompleted || job.Status == JobFailed {
+		return true
+	}
+
+	started := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &started
+	if err := q.save(ctx, job); err != nil {
+		log.Printf("jobs: save %s failed: %v", id, err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, q.cfg.Timeout)
+	session, err := q.run(runCtx, job)
+	cancel()
+
+	var rerr *RunError
+	if errors.As(err, &rerr) {
+		job.RunID = rerr.RunID
+	}
+
+	if ctx.Err() != nil {
+		// resumed from the run's checkpoints by whichever instance requeues it
+		job.Status = JobQueued
+		if err := q.save(context.WithoutCancel(ctx), job); err != nil {
+			log.Printf("jobs: save %s failed: %v", id, err)
+		}
+		return false
+	}
+
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = JobCompleted
+		job.Result = session
+		job.RunID = session.RunID
+	}
+	finished := time.Now()
+	job.FinishedAt = &finished
+
+	if job.Webhook != nil {
+		q.deliver(ctx, job)
+	}
+	if err := q.save(ctx, job); err != nil {
+		log.Printf("jobs: save %s failed: %v", id, err)
+	}
+	return true
+}
+
+// run starts the job's pipeline, or resumes it when an earlier attempt
+// was interrupted after creating its run. Only one worker holds a job,
+// so a run it left "running" is taken over rather than waited for.
+func (q *JobQueue) run(ctx context.Context, job *FiveWhyJob) (*FiveWhySession, error) {
+	owner := types.Owner{UserID: job.UserID, TenantID: job.TenantID}
+	if job.RunID != "" {
+		return q.service.resumeRun(ctx, owner, job.RunID, true)
+	}
+	return q.service.FiveWhy(ctx, job.SessionID, owner, job.Question, FiveWhyOptions{
+		OnRun: func(runID string) {
+			// a retry after a crash resumes this run instead of starting over
+			job.RunID = runID
+			if err := q.save(ctx, job); err != nil {
+				log.Printf("jobs: save %s failed: %v", job.ID, err)
+			}
+		},
+	})
+}
+
+// deliver POSTs the job's outcome to its callback URL, retrying with
+// exponential backoff on network errors and non-2xx responses.
+func (q *JobQueue) deliver(ctx context.Context, job *FiveWhyJob) {
+	hook := job.Webhook
+
+	body, err := json.Marshal(WebhookPayload{
+		JobID:  job.ID,
+		Status: job.Status,
+		RunID:  job.RunID,
+		Result: job.Result,
+		Error:  job.Error,
+	})
+	if err != nil {
+		hook.Error = err.Error()
+		return
+	}
+
+	for attempt := 1; attempt <= q.cfg.WebhookAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				hook.Error = ctx.Err().Error()
+				return
+			case <-time.After(time.Duration(1<<(attempt-2)) * time.Second):
+			}
+		}
+
+		hook.Attempts = attempt
+		status, err := q.post(ctx, hook.URL, job.ID, body)
+		hook.StatusCode = status
+		if err == nil {
+			delivered := time.Now()
+			hook.DeliveredAt = &delivered
+			hook.Error = ""
+			return
+		}
+		hook.Error = err.Error()
+	}
+}
+
+func (q *JobQueue) post(ctx context.Context, callbackURL, jobID string, body []byte) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, jobID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(q.cfg.WebhookSecret, timestamp, body))
+
+	resp, err := q.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("callback returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>" under
+// secret. Receivers recompute it to authenticate a callback and should
+// reject stale timestamps to stop replays.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ================================
+// Storage
+// ================================
+
+func (q *JobQueue) save(ctx context.Context, job *FiveWhyJob) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.redis.Set(ctx, jobKey(job.ID), b, q.cfg.TTL)
+}
+
+func (q *JobQueue) load(ctx context.Context, id string) (*FiveWhyJob, error) {
+	raw, err := q.redis.Get(ctx, jobKey(id))
+	if errors.Is(err, db.ErrNil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job FiveWhyJob
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// validateCallback rejects callbacks that are not http(s) or whose host
+// resolves to a non-public address. The dialer checks again on every
+// delivery, since DNS can change in between.
+func validateCallback(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidCallback
+	}
+
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return ErrPrivateCallback
+		}
+		return nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return ErrInvalidCallback
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return ErrPrivateCallback
+		}
+	}
+	return nil
+}
+
+// cgnat is the carrier-grade NAT range (RFC 6598), private in practice.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func publicIP(ip net.IP) bool {
+	return ip != nil &&
+		!ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified() &&
+		!cgnat.Contains(ip)
+}
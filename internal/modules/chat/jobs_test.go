@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"quavixAI/internal/db"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	const secret = "s3cret"
+
+	var hits atomic.Int32
+	received := make(chan WebhookPayload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt fails so the retry is exercised
+		if hits.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get(WebhookTimestampHeader)
+		want := "sha256=" + SignWebhook(secret, ts, body)
+		if got := r.Header.Get(WebhookSignatureHeader); got != want {
+			t.Errorf("signature %q, want %q", got, want)
+		}
+		if got := r.Header.Get(WebhookIDHeader); got != "job-1" {
+			t.Errorf("webhook id %q, want job-1", got)
+		}
+
+		var payload WebhookPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer srv.Close()
+
+	q := NewJobQueue(JobConfig{WebhookSecret: secret, HTTPClient: srv.Client()})
+	job := &FiveWhyJob{
+		ID:      "job-1",
+		Status:  JobCompleted,
+		RunID:   "run-1",
+		Result:  &FiveWhySession{SessionID: "s1"},
+		Webhook: &WebhookDelivery{URL: srv.URL},
+	}
+	q.deliver(context.Background(), job)
+
+	if job.Webhook.DeliveredAt == nil || job.Webhook.Attempts != 2 || job.Webhook.StatusCode != http.StatusOK {
+		t.Fatalf("delivery %+v, want delivered on attempt 2", job.Webhook)
+	}
+	payload := <-received
+	if payload.JobID != "job-1" || payload.RunID != "run-1" || payload.Status != JobCompleted {
+		t.Fatalf("payload %+v", payload)
+	}
+}
+
+func TestWebhookRefusesPrivateAddress(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	// the default client, which guards the dial
+	q := NewJobQueue(JobConfig{WebhookSecret: "s", WebhookAttempts: 1})
+	job := &FiveWhyJob{ID: "job-1", Status: JobCompleted, Webhook: &WebhookDelivery{URL: srv.URL}}
+	q.deliver(context.Background(), job)
+
+	if job.Webhook.DeliveredAt != nil || hits.Load() != 0 {
+		t.Fatalf("callback to loopback was delivered")
+	}
+	if !strings.Contains(job.Webhook.Error, ErrPrivateCallback.Error()) {
+		t.Fatalf("error %q, want %q", job.Webhook.Error, ErrPrivateCallback)
+	}
+}
+
+func TestValidateCallback(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://93.184.216.34/hook", nil},
+		{"ftp://93.184.216.34/hook", ErrInvalidCallback},
+		{"/relative", ErrInvalidCallback},
+		{"http://127.0.0.1:8080/hook", ErrPrivateCallback},
+		{"http://localhost/hook", ErrPrivateCallback},
+		{"http://[::1]/hook", ErrPrivateCallback},
+		{"http://10.1.2.3/hook", ErrPrivateCallback},
+		{"http://192.168.0.10/hook", ErrPrivateCallback},
+		{"http://169.254.169.254/latest/meta-data", ErrPrivateCallback},
+		{"http://100.64.0.1/hook", ErrPrivateCallback},
+		{"http://0.0.0.0/hook", ErrPrivateCallback},
+	}
+	for _, tt := range tests {
+		if err := validateCallback(context.Background(), tt.url); !errors.Is(err, tt.want) {
+			t.Errorf("validateCallback(%q) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+}
+
+func TestReapOnlyExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	rc := &db.RedisClient{Client: client}
+
+	a := NewJobQueue(JobConfig{Redis: rc})
+	b := NewJobQueue(JobConfig{Redis: rc})
+	for _, q := range []*JobQueue{a, b} {
+		if err := q.register(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a is running job-1 when b starts
+	if err := rc.Enqueue(ctx, jobProcessingKey(a.instance), "job-1"); err != nil {
+		t.Fatal(err)
+	}
+	b.reap(ctx)
+	if queued, _ := mr.List(jobQueueKey); len(queued) != 0 {
+		t.Fatalf("live instance's job was requeued: %v", queued)
+	}
+
+	// a stops renewing its lease while b keeps its own
+	mr.FastForward(jobLeaseTTL / 2)
+	if err := b.register(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mr.FastForward(jobLeaseTTL / 2)
+	a.reap(ctx) // a never reaps itself
+	if queued, _ := mr.List(jobQueueKey); len(queued) != 0 {
+		t.Fatalf("instance requeued its own jobs: %v", queued)
+	}
+
+	b.reap(ctx)
+	b.reap(ctx) // reaping again is a no-op
+	if queued, _ := mr.List(jobQueueKey); len(queued) != 1 || queued[0] != "job-1" {
+		t.Fatalf("queue %v, want job-1 once", queued)
+	}
+	if mr.Exists(jobProcessingKey(a.instance)) {
+		t.Fatalf("expired instance still holds jobs")
+	}
+	if members, _ := mr.Members(jobInstancesKey); len(members) != 1 || members[0] != b.instance {
+		t.Fatalf("instances %v, want only %s", members, b.instance)
+	}
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"quavixAI/internal/db"
 	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/types"
 	"quavixAI/internal/modules/vector"
 )
 
@@ -21,12 +25,44 @@ type MemoryMessage struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// SessionMemory is one page of a session, oldest message first. Total
+// counts every message still kept verbatim; older ones live on only in
+// Summary.
 type SessionMemory struct {
 	SessionID string          `json:"session_id"`
+	Summary   string          `json:"summary,omitempty"`
 	Messages  []MemoryMessage `json:"messages"`
+	Total     int64           `json:"total"`
+	Offset    int             `json:"offset"`
 	UpdatedAt time.Time       `json:"updated_at"`
 }
 
+// SessionPage selects messages counting back from the newest: Offset
+// skips the newest Offset messages and Limit (0 = all) caps the page.
+type SessionPage struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// RecallOptions must name the user; recall never crosses owners.
+type RecallOptions struct {
+	UserID   string   `json:"user_id"`
+	TenantID string   `json:"tenant_id"`
+	Types    []string `json:"types"`
+	Limit    int      `json:"limit"`
+	MinScore float64  `json:"min_score"`
+
+	// fusion weights for embedding vs keyword ranking (both zero = equal)
+	SemanticWeight float64 `json:"semantic_weight"`
+	LexicalWeight  float64 `json:"lexical_weight"`
+
+	// Rerank uses the configured Reranker, which may call the LLM;
+	// otherwise candidates keep their fused retrieval score.
+	Rerank bool `json:"rerank"`
+}
+
 type RetrievedMemory struct {
 	Documents []vector.Document `json:"documents"`
 	Context   string            `json:"context"`
@@ -36,17 +72,107 @@ type RetrievedMemory struct {
 // Memory Engine
 // ================================
 
+const (
+	// DefaultRecallMinScore drops weak semantic matches (score is 1/(1+d)
+	// under the default l2 metric) when a caller does not set its own.
+	DefaultRecallMinScore = 0.3
+
+	// DefaultRerankCandidates is how many candidates per requested result
+	// Recall fetches before reranking.
+	DefaultRerankCandidates = 4
+
+	defaultRecallLimit = 5
+
+	// DefaultSessionMaxLen caps the messages kept per session; older
+	// ones are trimmed on write.
+	DefaultSessionMaxLen = 200
+	DefaultSessionTTL    = 24 * time.Hour
+)
+
+type MemoryConfig struct {
+	Redis  *db.RedisClient
+	Vector vector.Store
+	LLM    *llm.Manager
+
+	Reranker         Reranker // used when RecallOptions.Rerank; nil = lexical overlap
+	RerankCandidates int
+	MinScore         float64
+
+	Importance ImportanceScorer // nil = rules keyed on Meta["type"]
+	Weights    RecallWeights
+
+	SessionMaxLen int           // messages kept per session
+	SessionTTL    time.Duration // refreshed on every append
+
+	Prompt    prompt.Builder
+	Summarize SummaryConfig
+
+	Graph *GraphStore // nil disables entity extraction
+}
+
 type MemoryEngine struct {
-	redis  *db.RedisClient
-	vector vector.Store
-	llm    *llm.Manager
+	redis      *db.RedisClient
+	vector     vector.Store
+	llm        *llm.Manager
+	reranker   Reranker
+	importance ImportanceScorer
+	rules      *RuleScorer
+
+	rerankCandidates int
+	minScore         float64
+	weights          RecallWeights
+
+	sessionMaxLen int
+	sessionTTL    time.Duration
+
+	prompt    prompt.Builder
+	summarize SummaryConfig
+	graph     *GraphStore
+
+	summarizing sync.Map // session ID -> in-flight background summary
 }
 
-func NewMemoryEngine(redis *db.RedisClient, vstore vector.Store, llmMgr *llm.Manager) *MemoryEngine {
+func NewMemoryEngine(cfg MemoryConfig) *MemoryEngine {
+	if cfg.Reranker == nil {
+		cfg.Reranker = NewLexicalReranker()
+	}
+	if cfg.RerankCandidates <= 0 {
+		cfg.RerankCandidates = DefaultRerankCandidates
+	}
+	if cfg.MinScore == 0 {
+		cfg.MinScore = DefaultRecallMinScore
+	}
+	rules := NewRuleScorer()
+	if cfg.Importance == nil {
+		cfg.Importance = rules
+	}
+	cfg.Weights.defaults()
+	if cfg.SessionMaxLen <= 0 {
+		cfg.SessionMaxLen = DefaultSessionMaxLen
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = DefaultSessionTTL
+	}
+	if cfg.Prompt == nil {
+		cfg.Prompt = prompt.NewBuilder()
+	}
+	cfg.Summarize.defaults()
+
 	return &MemoryEngine{
-		redis:  redis,
-		vector: vstore,
-		llm:    llmMgr,
+		redis:            cfg.Redis,
+		vector:           cfg.Vector,
+		llm:              cfg.LLM,
+		reranker:         cfg.Reranker,
+		importance:       cfg.Importance,
+		rules:            rules,
+		rerankCandidates: cfg.RerankCandidates,
+		minScore:         cfg.MinScore,
+		weights:          cfg.Weights,
+		sessionMaxLen:    cfg.SessionMaxLen,
+		sessionTTL:       cfg.SessionTTL,
+		prompt:           cfg.Prompt,
+		summarize:        cfg.Summarize,
+		graph:            cfg.Graph,
 	}
 }
 
@@ -54,111 +180,252 @@ func NewMemoryEngine(redis *db.RedisClient, vstore vector.Store, llmMgr *llm.Man
 // Session Memory (Redis)
 // ================================
 
-func (m *MemoryEngine) AppendSession(ctx context.Context, sessionID, role, content string) error {
+// Each session is a Redis list of JSON messages. Appends are a single
+// RPUSH + LTRIM + EXPIRE transaction, so concurrent requests in one
+// session never overwrite each other.
+func sessionKey(sessionID string) string {
+	return "session:" + sessionID + ":messages"
+}
+
+// The first user to write to a session owns it; the owner's set of
+// session IDs is what a purge walks.
+func sessionOwnerKey(sessionID string) string {
+	return "session:" + sessionID + ":owner"
+}
+
+func userSessionsKey(userID string) string {
+	return "user:" + userID + ":sessions"
+}
+
+// claimSession makes owner the holder of a new session, or checks that
+// they already are. Sessions of other users look like missing ones.
+func (m *MemoryEngine) claimSession(ctx context.Context, owner types.Owner, sessionID string) error {
+	holder, err := m.redis.Claim(ctx, sessionOwnerKey(sessionID), owner.UserID, m.sessionTTL)
+	if err != nil {
+		return err
+	}
+	if holder != owner.UserID {
+		return ErrSessionNotFound
+	}
+	return m.redis.AddToSet(ctx, userSessionsKey(owner.UserID), m.sessionTTL, sessionID)
+}
+
+func (m *MemoryEngine) checkSession(ctx context.Context, owner types.Owner, sessionID string) error {
+	holder, err := m.redis.Get(ctx, sessionOwnerKey(sessionID))
+	if errors.Is(err, db.ErrNil) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if holder != owner.UserID {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (m *MemoryEngine) AppendSession(ctx context.Context, owner types.Owner, sessionID, role, content string) error {
 	if sessionID == "" {
 		return errors.New("missing session id")
 	}
+	if owner.UserID == "" {
+		return errors.New("missing user id")
+	}
+	if m.redis == nil {
+		return errors.New("session store not configured")
+	}
 
-	key := "session:" + sessionID
+	if err := m.claimSession(ctx, owner, sessionID); err != nil {
+		return err
+	}
 
-	msg := MemoryMessage{
+	b, err := json.Marshal(MemoryMessage{
 		Role:      role,
 		Content:   content,
 		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return err
 	}
 
-	var session SessionMemory
-
-	data, _ := m.redis.Get(ctx, key)
-	if data != "" {
-		_ = json.Unmarshal([]byte(data), &session)
+	n, err := m.redis.AppendList(ctx, sessionKey(sessionID), int64(m.sessionMaxLen), m.sessionTTL, string(b))
+	if err != nil {
+		return err
 	}
 
-	session.SessionID = sessionID
-	session.Messages = append(session.Messages, msg)
-	session.UpdatedAt = time.Now()
-
-	b, _ := json.Marshal(session)
-	_ = m.redis.Set(ctx, key, string(b), 24*time.Hour)
+	// the running summary lives as long as the session it belongs to
+	if err := m.redis.Expire(ctx, summaryKey(sessionID), m.sessionTTL); err != nil {
+		return err
+	}
 
+	if n > int64(m.summarize.KeepRecent) && m.llm != nil {
+		m.maybeSummarize(context.WithoutCancel(ctx), sessionID)
+	}
 	return nil
 }
 
-func (m *MemoryEngine) GetSession(ctx context.Context, sessionID string) (*SessionMemory, error) {
-	key := "session:" + sessionID
-	data, err := m.redis.Get(ctx, key)
-	if err != nil || data == "" {
-		return nil, errors.New("session not found")
+// GetSession returns one page of a session owned by owner, oldest
+// message first.
+func (m *MemoryEngine) GetSession(ctx context.Context, owner types.Owner, sessionID string, page SessionPage) (*SessionMemory, error) {
+	if sessionID == "" {
+		return nil, errors.New("missing session id")
+	}
+	if m.redis == nil {
+		return nil, errors.New("session store not configured")
+	}
+	if page.Offset < 0 || page.Limit < 0 {
+		return nil, errors.New("invalid session page")
+	}
+	if err := m.checkSession(ctx, owner, sessionID); err != nil {
+		return nil, err
+	}
+
+	// LRANGE indices counted from the newest message
+	stop := int64(-(page.Offset + 1))
+	start := int64(0)
+	if page.Limit > 0 {
+		start = stop - int64(page.Limit) + 1
 	}
 
-	var session SessionMemory
-	if err := json.Unmarshal([]byte(data), &session); err != nil {
+	items, total, err := m.redis.ListPage(ctx, sessionKey(sessionID), start, stop)
+	if err != nil {
 		return nil, err
 	}
+	if total == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	session := &SessionMemory{
+		SessionID: sessionID,
+		Messages:  make([]MemoryMessage, 0, len(items)),
+		Total:     total,
+		Offset:    page.Offset,
+	}
+	for _, item := range items {
+		var msg MemoryMessage
+		if err := json.Unmarshal([]byte(item), &msg); err != nil {
+			return nil, fmt.Errorf("corrupt session message: %w", err)
+		}
+		session.Messages = append(session.Messages, msg)
+	}
+	if n := len(session.Messages); n > 0 {
+		session.UpdatedAt = session.Messages[n-1].Timestamp
+	}
 
-	return &session, nil
+	summary, err := m.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if summary != nil {
+		session.Summary = summary.Text
+	}
+
+	return session, nil
 }
 
 // ================================
 // Compression (LLM Summarization)
 // ================================
 
-func (m *MemoryEngine) CompressSession(ctx context.Context, sessionID string) (string, error) {
-	session, err := m.GetSession(ctx, sessionID)
+func (m *MemoryEngine) CompressSession(ctx context.Context, owner types.Owner, sessionID string) (string, error) {
+	session, err := m.GetSession(ctx, owner, sessionID, SessionPage{})
 	if err != nil {
 		return "", err
 	}
 
-	b, _ := json.Marshal(session.Messages)
-
-	summaryPrompt := "Summarize the following conversation into long-term semantic memory:\n" + string(b)
-
 	resp, err := m.llm.Generate(ctx, llm.Request{
 		Mode:   llm.ModeAnalysis,
-		Prompt: summaryPrompt,
+		Prompt: m.prompt.BuildMemorySummaryPrompt(session.Summary, formatConversation(session.Messages)),
 	})
 	if err != nil {
 		return "", err
 	}
 
+	text, err := m.prompt.ParseMemorySummary(resp.Text)
+	if err != nil {
+		return "", err
+	}
+
 	// store compressed memory into vector DB
-	emb, _ := m.llm.Embed(ctx, resp.Text)
+	emb, err := m.llm.Embed(ctx, text)
+	if err != nil {
+		return "", err
+	}
 
-	_ = m.vector.Store(ctx, vector.Document{
+	meta := owner.Meta()
+	meta["type"] = "session_summary"
+	meta["sessionID"] = sessionID
+	meta = m.scoreMemory(ctx, text, meta)
+
+	err = m.vector.Store(ctx, vector.Document{
 		ID:      sessionID + "_summary",
-		Content: resp.Text,
+		Content: text,
 		Vector:  emb,
-		Meta: map[string]string{
-			"type":      "session_summary",
-			"sessionID": sessionID,
-		},
+		Meta:    meta,
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return resp.Text, nil
+	return text, nil
 }
 
 // ================================
 // Recall (Vector Search)
 // ================================
 
-func (m *MemoryEngine) Recall(ctx context.Context, query string, limit int) (*RetrievedMemory, error) {
+func (m *MemoryEngine) Recall(ctx context.Context, query string, opts RecallOptions) (*RetrievedMemory, error) {
 	if query == "" {
 		return nil, errors.New("empty query")
 	}
+	if opts.UserID == "" {
+		return nil, errors.New("recall requires a user id")
+	}
 
 	emb, err := m.llm.Embed(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	docs, err := m.vector.Search(ctx, emb, limit)
+	if opts.MinScore == 0 {
+		opts.MinScore = m.minScore
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+
+	// over-fetch, rerank, keep the best limit
+	search := recallSearchOptions(opts)
+	search.Limit = limit * m.rerankCandidates
+
+	docs, err := vector.HybridSearch(ctx, m.vector, query, emb, vector.HybridOptions{
+		SearchOptions:  search,
+		SemanticWeight: opts.SemanticWeight,
+		LexicalWeight:  opts.LexicalWeight,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.Rerank && len(docs) > 1 {
+		docs, err = m.reranker.Rerank(ctx, query, docs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	docs = rankMemories(ctx, docs, m.weights, m.rules, now)
+	if len(docs) > limit {
+		docs = docs[:limit]
+	}
+
+	m.touch(ctx, docs, now)
+
 	ctxStr := ""
 	for _, d := range docs {
-		ctxStr += d.Content + "\n"
+		ctxStr += fmt.Sprintf("[score %.3f] %s\n", d.Score, d.Content)
 	}
 
 	return &RetrievedMemory{
@@ -171,20 +438,28 @@ func (m *MemoryEngine) Recall(ctx context.Context, query string, limit int) (*Re
 // Hybrid Retrieval (Session + Vector)
 // ================================
 
-func (m *MemoryEngine) HybridContext(ctx context.Context, sessionID, query string, limit int) (string, error) {
+func (m *MemoryEngine) HybridContext(ctx context.Context, owner types.Owner, sessionID, query string, limit int) (string, error) {
 	var contextStr string
 
 	// session memory
-	session, _ := m.GetSession(ctx, sessionID)
+	session, err := m.GetSession(ctx, owner, sessionID, SessionPage{})
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return "", err
+	}
 	if session != nil {
-		for _, msg := range session.Messages {
-			contextStr += msg.Role + ": " + msg.Content + "\n"
+		if session.Summary != "" {
+			contextStr += "--- Session Summary ---\n" + session.Summary + "\n\n--- Recent Messages ---\n"
 		}
+		contextStr += formatConversation(session.Messages)
 	}
 
-	// vector memory
-	recall, err := m.Recall(ctx, query, limit)
-	if err == nil {
+	// vector memory (only hits above the relevance threshold)
+	recall, err := m.Recall(ctx, query, RecallOptions{
+		UserID:   owner.UserID,
+		TenantID: owner.TenantID,
+		Limit:    limit,
+	})
+	if err == nil && len(recall.Documents) > 0 {
 		contextStr += "\n--- Semantic Memory ---\n"
 		contextStr += recall.Context
 	}
@@ -196,26 +471,152 @@ func (m *MemoryEngine) HybridContext(ctx context.Context, sessionID, query strin
 // Long-term Memory Store
 // ================================
 
-func (m *MemoryEngine) StoreLongTerm(ctx context.Context, content string, meta map[string]string) error {
+// StoreLongTerm embeds and stores content for owner. The ownership tags
+// always win over keys of the same name in meta.
+func (m *MemoryEngine) StoreLongTerm(ctx context.Context, owner types.Owner, content string, meta map[string]interface{}) error {
+	if owner.UserID == "" {
+		return errors.New("missing user id")
+	}
+
 	emb, err := m.llm.Embed(ctx, content)
 	if err != nil {
 		return err
 	}
 
+	tagged := make(map[string]interface{}, len(meta)+2)
+	for k, v := range meta {
+		tagged[k] = v
+	}
+	for k, v := range owner.Meta() {
+		tagged[k] = v
+	}
+
 	doc := vector.Document{
 		ID:      generateMemoryID(),
 		Content: content,
 		Vector:  emb,
-		Meta:    meta,
+		Meta:    m.scoreMemory(ctx, content, tagged),
 	}
 
 	return m.vector.Store(ctx, doc)
 }
 
+// scoreMemory returns a copy of meta with an importance score (unless
+// the caller set one) and the initial last-accessed time.
+func (m *MemoryEngine) scoreMemory(ctx context.Context, content string, meta map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(meta)+2)
+	for k, v := range meta {
+		out[k] = v
+	}
+
+	if _, ok := vector.MetaNumber(out[MetaImportance]); !ok {
+		score, err := m.importance.Score(ctx, content, out)
+		if err != nil {
+			score, _ = m.rules.Score(ctx, content, out)
+		}
+		out[MetaImportance] = score
+	}
+	out[MetaLastAccessed] = time.Now().UTC().Format(time.RFC3339)
+
+	return out
+}
+
+// touch refreshes the recency of recalled memories. It is best-effort:
+// stores without MetaUpdater simply keep ranking by creation time.
+func (m *MemoryEngine) touch(ctx context.Context, docs []vector.Document, now time.Time) {
+	updater, ok := m.vector.(vector.MetaUpdater)
+	if !ok || len(docs) == 0 {
+		return
+	}
+
+	ids := make([]string, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+
+	_ = updater.UpdateMeta(ctx, ids, map[string]interface{}{
+		MetaLastAccessed: now.UTC().Format(time.RFC3339),
+	})
+}
+
+// ================================
+// Purge
+// ================================
+
+// PurgeSessions deletes every session userID still owns, with its
+// summary, and returns how many there were.
+func (m *MemoryEngine) PurgeSessions(ctx context.Context, userID string) (int64, error) {
+	if m.redis == nil {
+		return 0, nil
+	}
+
+	ids, err := m.redis.SetMembers(ctx, userSessionsKey(userID))
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	keys := []string{userSessionsKey(userID)}
+	for _, id := range ids {
+		// the entry may outlive a session that someone else has since claimed
+		if err := m.checkSession(ctx, types.Owner{UserID: userID}, id); err != nil {
+			if errors.Is(err, ErrSessionNotFound) {
+				continue
+			}
+			return 0, err
+		}
+		keys = append(keys, sessionKey(id), summaryKey(id), sessionOwnerKey(id))
+		purged++
+	}
+
+	if _, err := m.redis.DeleteKeys(ctx, keys...); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// ================================
+// Retention
+// ================================
+
+// DefaultRetentionPolicies bounds every document type the chat and LLM
+// modules write. Raw responses are the noisiest and go first; root
+// causes are the most useful to recall and are kept the longest.
+func DefaultRetentionPolicies() []vector.RetentionPolicy {
+	const day = 24 * time.Hour
+	return []vector.RetentionPolicy{
+		{Type: llm.MemoryTypeResponse, MaxAge: 7 * day, MaxPerUser: 200},
+		{Type: "question", MaxAge: 180 * day, MaxPerUser: 500},
+		{Type: "solution", MaxAge: 180 * day, MaxPerUser: 500},
+		{Type: "root_cause", MaxAge: 365 * day, MaxPerUser: 1000},
+		{Type: "session_summary", MaxAge: 90 * day, MaxPerUser: 100},
+	}
+}
+
 // ================================
 // Helpers
 // ================================
 
+// recallSearchOptions scopes a search to the caller's own documents.
+func recallSearchOptions(opts RecallOptions) vector.SearchOptions {
+	search := vector.SearchOptions{
+		Limit:    opts.Limit,
+		MinScore: opts.MinScore,
+	}
+	search.Equals = map[string]interface{}{types.MetaUserID: opts.UserID}
+	if opts.TenantID != "" {
+		search.Equals[types.MetaTenantID] = opts.TenantID
+	}
+	if len(opts.Types) > 0 {
+		types := make([]interface{}, len(opts.Types))
+		for i, t := range opts.Types {
+			types[i] = t
+		}
+		search.In = map[string][]interface{}{"type": types}
+	}
+	return search
+}
+
 func generateMemoryID() string {
 	return time.Now().Format("20060102150405.000000000")
 }
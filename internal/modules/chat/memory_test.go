@@ -0,0 +1,122 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"quavixAI/internal/db"
+	"quavixAI/internal/modules/types"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestMemory(t *testing.T, maxLen int) *MemoryEngine {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return NewMemoryEngine(MemoryConfig{
+		Redis:         &db.RedisClient{Client: client},
+		SessionMaxLen: maxLen,
+	})
+}
+
+func TestAppendSessionConcurrent(t *testing.T) {
+	const (
+		maxLen  = 50
+		writers = 8
+		perEach = 40
+	)
+	m := newTestMemory(t, maxLen)
+	ctx := context.Background()
+	owner := types.Owner{UserID: "u1"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perEach)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perEach; i++ {
+				content := fmt.Sprintf("w%d-%d", w, i)
+				if err := m.AppendSession(ctx, owner, "s1", "user", content); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("append: %v", err)
+	}
+
+	session, err := m.GetSession(ctx, owner, "s1", SessionPage{})
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	if session.Total != maxLen || len(session.Messages) != maxLen {
+		t.Fatalf("got total %d, %d messages; want %d", session.Total, len(session.Messages), maxLen)
+	}
+
+	// every writer's messages keep their relative order after trimming
+	last := make(map[int]int)
+	for _, msg := range session.Messages {
+		var w, i int
+		if _, err := fmt.Sscanf(msg.Content, "w%d-%d", &w, &i); err != nil {
+			t.Fatalf("unexpected message %q", msg.Content)
+		}
+		if prev, ok := last[w]; ok && i <= prev {
+			t.Fatalf("writer %d out of order: %d after %d", w, i, prev)
+		}
+		last[w] = i
+	}
+}
+
+func TestAppendSessionOtherOwner(t *testing.T) {
+	m := newTestMemory(t, 10)
+	ctx := context.Background()
+
+	if err := m.AppendSession(ctx, types.Owner{UserID: "u1"}, "s1", "user", "hi"); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	other := types.Owner{UserID: "u2"}
+	if err := m.AppendSession(ctx, other, "s1", "user", "mine now"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("append by other owner: got %v, want ErrSessionNotFound", err)
+	}
+	if _, err := m.GetSession(ctx, other, "s1", SessionPage{}); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("get by other owner: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestGetSessionPage(t *testing.T) {
+	m := newTestMemory(t, 10)
+	ctx := context.Background()
+	owner := types.Owner{UserID: "u1"}
+
+	for i := 0; i < 15; i++ {
+		if err := m.AppendSession(ctx, owner, "s1", "user", fmt.Sprint(i)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	// the newest 10 survive; skip the newest 2, take 3
+	page, err := m.GetSession(ctx, owner, "s1", SessionPage{Offset: 2, Limit: 3})
+	if err != nil {
+		t.Fatalf("get session: %v", err)
+	}
+	var got []string
+	for _, msg := range page.Messages {
+		got = append(got, msg.Content)
+	}
+	if fmt.Sprint(got) != "[10 11 12]" || page.Total != 10 {
+		t.Fatalf("got %v (total %d), want [10 11 12] (total 10)", got, page.Total)
+	}
+}
@@ -0,0 +1,240 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+
+	"github.com/google/uuid"
+)
+
+// ================================
+// Analysis Methodologies
+// ================================
+
+// Methodology is one way of investigating a problem (5-Why, fishbone,
+// fault tree, ...). Each brings its own prompts, steps and result type.
+type Methodology interface {
+	Name() string
+	Analyze(ctx context.Context, req AnalysisRequest) (Analysis, error)
+}
+
+// Analysis is a methodology's result. Summary is its main finding in
+// one sentence; it is kept as the investigation's root cause memory.
+type Analysis interface {
+	Summary() string
+}
+
+// AnalysisRequest is one investigation. RunID is the report's ID; a
+// methodology that stores memories keys them by it, so several analyses
+// in one session do not overwrite each other.
+type AnalysisRequest struct {
+	Owner     types.Owner
+	RunID     string
+	SessionID string
+	Problem   string
+}
+
+// AnalysisReport wraps any methodology's result with the bookkeeping
+// every analysis shares.
+type AnalysisReport struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	UserID    string    `json:"user_id"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	SessionID string    `json:"session_id"`
+	Problem   string    `json:"problem"`
+	Summary   string    `json:"summary"`
+	Result    Analysis  `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var ErrUnknownMethod = errors.New("unknown analysis method")
+
+// MethodConfig is shared by the LLM-driven methodologies.
+type MethodConfig struct {
+	LLM     *llm.Manager
+	Prompt  prompt.Builder
+	Workers int // concurrent LLM calls within one analysis
+}
+
+func (c *MethodConfig) defaults() {
+	if c.Prompt == nil {
+		c.Prompt = prompt.NewBuilder()
+	}
+	if c.Workers <= 0 {
+		c.Workers = DefaultStageWorkers
+	}
+}
+
+// ================================
+// Registry
+// ================================
+
+type MethodRegistry struct {
+	mu      sync.RWMutex
+	methods map[string]Methodology
+}
+
+func NewMethodRegistry(methods ...Methodology) *MethodRegistry {
+	r := &MethodRegistry{methods: make(map[string]Methodology)}
+	for _, m := range methods {
+		r.Register(m)
+	}
+	return r
+}
+
+// Register adds m, replacing any methodology of the same name.
+func (r *MethodRegistry) Register(m Methodology) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[m.Name()] = m
+}
+
+func (r *MethodRegistry) Get(name string) (Methodology, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.methods[name]
+	return m, ok
+}
+
+func (r *MethodRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.methods))
+	for name := range r.methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ================================
+// Service API
+// ================================
+
+// Methods lists the registered methodologies.
+func (s *Service) Methods() []string {
+	return s.methods.Names()
+}
+
+// Analyze runs method over problem for owner and records the result in
+// the session, long-term memory and Postgres.
+func (s *Service) Analyze(ctx context.Context, method, sessionID string, owner types.Owner, problem string) (*AnalysisReport, error) {
+	m, ok := s.methods.Get(method)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownMethod, method)
+	}
+	problem = strings.TrimSpace(problem)
+	if problem == "" {
+		return nil, errors.New("empty problem")
+	}
+	ctx = types.WithOwner(ctx, owner)
+
+	report := &AnalysisReport{
+		ID:        uuid.NewString(),
+		Method:    m.Name(),
+		UserID:    owner.UserID,
+		TenantID:  owner.TenantID,
+		SessionID: sessionID,
+		Problem:   problem,
+		CreatedAt: time.Now(),
+	}
+	if report.SessionID == "" {
+		report.SessionID = report.ID
+	}
+
+	if err := s.appendRun(ctx, owner, report.SessionID, "user", problem); err != nil {
+		return nil, err
+	}
+
+	result, err := m.Analyze(ctx, AnalysisRequest{
+		Owner:     owner,
+		RunID:     report.ID,
+		SessionID: report.SessionID,
+		Problem:   problem,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Result = result
+	report.Summary = result.Summary()
+
+	if err := s.appendRun(ctx, owner, report.SessionID, "assistant", report.Summary); err != nil {
+		return nil, err
+	}
+
+	// keyed by analysis, so several in one session all stay; the 5-Why
+	// pipeline stores its own memories
+	if s.vector != nil && m.Name() != MethodFiveWhy {
+		for _, doc := range []vector.Document{
+			{ID: report.ID, Content: problem, Meta: analysisMeta(owner, "question", report)},
+			{ID: report.ID + "_rca", Content: report.Summary, Meta: analysisMeta(owner, "root_cause", report)},
+		} {
+			if doc.Content == "" {
+				continue
+			}
+			emb, err := s.llm.Embed(ctx, doc.Content)
+			if err != nil {
+				log.Printf("analysis %s: embedding %s memory failed: %v", report.ID, doc.Meta["type"], err)
+				continue
+			}
+			doc.Vector = emb
+			if err := s.vector.Store(ctx, doc); err != nil {
+				log.Printf("analysis %s: storing %s memory failed: %v", report.ID, doc.Meta["type"], err)
+			}
+		}
+	}
+
+	if s.repo != nil {
+		_ = s.repo.SaveAnalysis(ctx, report)
+	}
+
+	return report, nil
+}
+
+func analysisMeta(owner types.Owner, memoryType string, report *AnalysisReport) map[string]interface{} {
+	meta := memoryMeta(owner, memoryType)
+	meta["method"] = report.Method
+	meta[MetaSessionID] = report.SessionID
+	return meta
+}
+
+// ================================
+// 5-Why
+// ================================
+
+// MethodFiveWhy is the adaptive why-tree pipeline run by Orchestrator.
+const MethodFiveWhy = "5why"
+
+func (o *Orchestrator) Name() string {
+	return MethodFiveWhy
+}
+
+func (o *Orchestrator) Analyze(ctx context.Context, req AnalysisRequest) (Analysis, error) {
+	session := &FiveWhySession{
+		SessionID: req.SessionID,
+		RunID:     req.RunID,
+		Steps:     []types.FiveWhyStep{},
+		CreatedAt: time.Now(),
+	}
+	if err := o.Execute(ctx, req.Owner, req.Problem, session, nil); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *FiveWhySession) Summary() string {
+	return s.RootCause.RootCause
+}
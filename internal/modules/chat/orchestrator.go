@@ -2,13 +2,18 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"quavixAI/internal/modules/llm"
 	"quavixAI/internal/modules/prompt"
 	"quavixAI/internal/modules/types"
 	"quavixAI/internal/modules/vector"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // ================================
@@ -17,29 +22,103 @@ import (
 
 type FiveWhySession struct {
 	SessionID string                 `json:"session_id"`
-	Steps     []types.FiveWhyStep    `json:"steps"`
+	RunID     string                 `json:"run_id,omitempty"`
+	Steps     []types.FiveWhyStep    `json:"steps"` // the tree in pre-order
+	Tree      *types.WhyNode         `json:"tree,omitempty"`
+	Timings   []StageTiming          `json:"timings,omitempty"`
 	RootCause types.RootCauseResult  `json:"root_cause"`
 	Solution  types.SolutionResult   `json:"solution"`
 	Reframed  types.ReframedQuestion `json:"reframed"`
 	CreatedAt time.Time              `json:"created_at"`
+
+	// SimilarIncidents are earlier investigations found before the run;
+	// ReusedFrom is the run whose analysis was returned instead of one.
+	SimilarIncidents []SimilarIncident `json:"similar_incidents,omitempty"`
+	ReusedFrom       string            `json:"reused_from,omitempty"`
 }
 
 // ================================
 // Orchestrator
 // ================================
 
+// DepthConfig bounds adaptive runs. A branch stops once the evaluation
+// calls it a root cause (but not before MinDepth) or at MaxDepth.
+// MaxBranches caps the children of one WHY, the main chain included,
+// and MaxNodes caps the WHYs of a whole run.
+type DepthConfig struct {
+	MinDepth    int
+	MaxDepth    int
+	MaxBranches int
+	MaxNodes    int
+}
+
+const (
+	DefaultMinDepth    = 2
+	DefaultMaxDepth    = 7
+	DefaultMaxBranches = 3
+	DefaultMaxNodes    = 15
+)
+
+func (c *DepthConfig) defaults() {
+	if c.MinDepth <= 0 {
+		c.MinDepth = DefaultMinDepth
+	}
+	if c.MaxDepth <= 0 {
+		c.MaxDepth = DefaultMaxDepth
+	}
+	if c.MaxDepth < c.MinDepth {
+		c.MaxDepth = c.MinDepth
+	}
+	if c.MaxBranches <= 0 {
+		c.MaxBranches = DefaultMaxBranches
+	}
+	if c.MaxNodes <= 0 {
+		c.MaxNodes = DefaultMaxNodes
+	}
+}
+
+type OrchestratorConfig struct {
+	LLM    *llm.Manager
+	Vector vector.Store
+	Prompt prompt.Builder
+	Depth  DepthConfig
+	Debate DebateConfig
+
+	// Evidence grounds auto runs in retrieved documents
+	Evidence EvidenceConfig
+
+	Workers int // concurrent pipeline stages
+}
+
 type Orchestrator struct {
-	llm    *llm.Manager // ✅ POINTER
-	vector vector.Store
-	prompt prompt.Builder
+	llm      *llm.Manager // ✅ POINTER
+	vector   vector.Store
+	prompt   prompt.Builder
+	depth    DepthConfig
+	debate   DebateConfig
+	evidence EvidenceConfig
+	workers  int
 }
 
 // ✅ POINTER IN CONSTRUCTOR
-func NewOrchestrator(llmMgr *llm.Manager, vstore vector.Store, pb prompt.Builder) *Orchestrator {
+func NewOrchestrator(cfg OrchestratorConfig) *Orchestrator {
+	cfg.Depth.defaults()
+	cfg.Debate.defaults()
+	cfg.Evidence.defaults()
+	if cfg.Prompt == nil {
+		cfg.Prompt = prompt.NewBuilder()
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultStageWorkers
+	}
 	return &Orchestrator{
-		llm:    llmMgr,
-		vector: vstore,
-		prompt: pb,
+		llm:      cfg.LLM,
+		vector:   cfg.Vector,
+		prompt:   cfg.Prompt,
+		depth:    cfg.Depth,
+		debate:   cfg.Debate,
+		evidence: cfg.Evidence,
+		workers:  cfg.Workers,
 	}
 }
 
@@ -49,164 +128,430 @@ func NewOrchestrator(llmMgr *llm.Manager, vstore vector.Store, pb prompt.Builder
 
 func (o *Orchestrator) RunFiveWhy(
 	ctx context.Context,
+	owner types.Owner,
 	sessionID string,
 	userQuestion string,
 ) (*FiveWhySession, error) {
 
-	if userQuestion == "" {
-		return nil, errors.New("empty question")
-	}
-
 	session := &FiveWhySession{
 		SessionID: sessionID,
 		Steps:     []types.FiveWhyStep{},
 		CreatedAt: time.Now(),
 	}
 
-	currentQuestion := userQuestion
-
-	// ================================
-	// 5 WHY LOOP
-	// ================================
-	for i := 1; i <= 5; i++ {
-
-		// WHY prompt
-		whyPrompt := o.prompt.BuildFiveWhyPrompt(i, currentQuestion)
+	if err := o.Execute(ctx, owner, userQuestion, session, nil); err != nil {
+		return nil, err
+	}
 
-		resp, err := o.llm.Generate(ctx, llm.Request{
-			Mode:   llm.ModeReasoning,
-			Prompt: whyPrompt,
-		})
-		if err != nil {
-			return nil, err
-		}
+	return session, nil
+}
 
-		// Evaluation
-		analysisPrompt := o.prompt.BuildEvaluationPrompt(currentQuestion, resp.Text)
+// Checkpoint lets a pipeline be resumed. Stages listed in Done were
+// completed by an earlier attempt and are skipped; Save is called after
+// each stage succeeds, while no other stage can touch the session.
+type Checkpoint struct {
+	Done map[string]bool
+	Save func(ctx context.Context, stage string) error
+}
 
-		analysisResp, err := o.llm.Generate(ctx, llm.Request{
-			Mode:   llm.ModeAnalysis,
-			Prompt: analysisPrompt,
-		})
-		if err != nil {
-			return nil, err
-		}
+func (c *Checkpoint) done(stage string) bool {
+	return c != nil && c.Done[stage]
+}
 
-		step := types.FiveWhyStep{
-			Level:    i,
-			Question: currentQuestion,
-			Answer:   resp.Text,
-			Analysis: analysisResp.Text,
-		}
+// Execute runs the whole pipeline, WHY tree included, into session. With
+// a checkpoint it picks up after the stages already done; session must
+// then hold their results.
+//
+//	why_tree ── root_cause ── [debate] ─┬─ solution ── store_solution
+//	                                    ├─ reframe
+//	                                    └─ store_root_cause
+//	store_question
+func (o *Orchestrator) Execute(
+	ctx context.Context,
+	owner types.Owner,
+	userQuestion string,
+	session *FiveWhySession,
+	cp *Checkpoint,
+) error {
 
-		session.Steps = append(session.Steps, step)
+	if userQuestion == "" {
+		return errors.New("empty question")
+	}
 
-		// Generate next WHY
-		nextWhyPrompt := o.prompt.BuildNextWhyPrompt(resp.Text)
+	tree := func(ctx context.Context) (*types.WhyNode, error) {
+		budget := &whyBudget{left: o.depth.MaxNodes}
+		budget.take() // the first WHY
+		return o.expand(ctx, owner, 1, "1", userQuestion, budget, newEvidenceSet())
+	}
+	return o.run(ctx, owner, userQuestion, session, cp, tree)
+}
 
-		nextResp, err := o.llm.Generate(ctx, llm.Request{
-			Mode:   llm.ModeReasoning,
-			Prompt: nextWhyPrompt,
-		})
-		if err != nil {
-			return nil, err
-		}
+// whyBudget is shared by every branch of one run.
+type whyBudget struct {
+	mu   sync.Mutex
+	left int
+}
 
-		currentQuestion = nextResp.Text
+func (b *whyBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.left <= 0 {
+		return false
 	}
+	b.left--
+	return true
+}
 
-	// ================================
-	// ROOT CAUSE EXTRACTION
-	// ================================
-
-	rcaPrompt := o.prompt.BuildRootCausePrompt(session.Steps)
+// give returns a node taken for a WHY that was never asked.
+func (b *whyBudget) give() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.left++
+}
 
-	rcaResp, err := o.llm.Generate(ctx, llm.Request{
-		Mode:   llm.ModeDiagnosis,
-		Prompt: rcaPrompt,
+// expand asks and evaluates one WHY, then follows the main chain and
+// every branch the verdict reports, up to workers at a time. The caller
+// has already taken the WHY's node from the budget; a child's node is
+// taken before its question is generated, so a spent budget costs no
+// further LLM calls.
+func (o *Orchestrator) expand(ctx context.Context, owner types.Owner, level int, branch, question string, budget *whyBudget, evidence *evidenceSet) (*types.WhyNode, error) {
+	docs := o.gather(ctx, owner, question, evidence)
+	resp, err := o.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeReasoning,
+		Prompt: o.prompt.BuildFiveWhyPrompt(level, question, docs),
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	var rootCause types.RootCauseResult
-	if err := o.prompt.ParseRootCause(rcaResp.Text, &rootCause); err != nil {
+	verdict, err := o.EvaluateAnswer(ctx, question, resp.Text)
+	if err != nil {
 		return nil, err
 	}
 
-	session.RootCause = rootCause
+	node := &types.WhyNode{Step: types.FiveWhyStep{
+		Level:       level,
+		Question:    question,
+		Answer:      resp.Text,
+		Analysis:    verdict.Analysis,
+		Branch:      branch,
+		IsRootCause: verdict.IsRootCause,
+		DepthScore:  verdict.DepthScore,
+	}}
+	node.Step.Citations, node.Step.InvalidCitations = resolveCitations(resp.Text, docs)
+	if o.stop(level, verdict) {
+		return node, nil
+	}
 
-	// ================================
-	// SOLUTION SYNTHESIS
-	// ================================
+	// the answer itself continues the main chain; each branch forks
+	seeds := append([]string{resp.Text}, verdict.Branches...)
+	if len(seeds) > o.depth.MaxBranches {
+		seeds = seeds[:o.depth.MaxBranches]
+	}
 
-	solutionPrompt := o.prompt.BuildSolutionPrompt(rootCause, session.Steps)
+	children := make([]*types.WhyNode, len(seeds))
 
-	solResp, err := o.llm.Generate(ctx, llm.Request{
-		Mode:   llm.ModePlanning,
-		Prompt: solutionPrompt,
-	})
-	if err != nil {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(o.workers)
+	for i, seed := range seeds {
+		if !budget.take() {
+			break
+		}
+		g.Go(func() error {
+			next, err := o.NextWhy(gctx, seed)
+			if err != nil {
+				budget.give()
+				return err
+			}
+			children[i], err = o.expand(gctx, owner, level+1, fmt.Sprintf("%s.%d", branch, i+1), next, budget, evidence)
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
 		return nil, err
 	}
+	for _, child := range children {
+		if child != nil {
+			node.Children = append(node.Children, child)
+		}
+	}
 
-	var solution types.SolutionResult
-	if err := o.prompt.ParseSolution(solResp.Text, &solution); err != nil {
-		return nil, err
+	return node, nil
+}
+
+func (o *Orchestrator) stop(level int, verdict types.EvaluationVerdict) bool {
+	if level >= o.depth.MaxDepth {
+		return true
 	}
+	return verdict.IsRootCause && level >= o.depth.MinDepth
+}
 
-	session.Solution = solution
+// ================================
+// Interactive Steps
+// ================================
 
-	// ================================
-	// QUESTION REFRAMING
-	// ================================
+// AskWhy generates the WHY question for level of an interactive run.
+func (o *Orchestrator) AskWhy(ctx context.Context, level int, problem string) (string, error) {
+	resp, err := o.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeReasoning,
+		Prompt: o.prompt.BuildFiveWhyPrompt(level, problem, nil),
+	})
+	if err != nil {
+		return "", err
+	}
+	return o.prompt.ParseWhyQuestion(resp.Text)
+}
 
-	reframePrompt := o.prompt.BuildReframePrompt(userQuestion, rootCause)
+// EvaluateAnswer analyses an answer to a WHY question and decides
+// whether it reached the root cause or forks into several causes.
+func (o *Orchestrator) EvaluateAnswer(ctx context.Context, question, answer string) (types.EvaluationVerdict, error) {
+	var verdict types.EvaluationVerdict
 
-	reframeResp, err := o.llm.Generate(ctx, llm.Request{
+	resp, err := o.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: o.prompt.BuildEvaluationPrompt(question, answer),
+	})
+	if err != nil {
+		return verdict, err
+	}
+
+	err = o.prompt.ParseEvaluation(resp.Text, &verdict)
+	return verdict, err
+}
+
+// NextWhy derives the next, deeper WHY question from an answer.
+func (o *Orchestrator) NextWhy(ctx context.Context, answer string) (string, error) {
+	resp, err := o.llm.Generate(ctx, llm.Request{
 		Mode:   llm.ModeReasoning,
-		Prompt: reframePrompt,
+		Prompt: o.prompt.BuildNextWhyPrompt(answer),
 	})
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return o.prompt.ParseWhyQuestion(resp.Text)
+}
 
-	var reframed types.ReframedQuestion
-	if err := o.prompt.ParseReframe(reframeResp.Text, &reframed); err != nil {
-		return nil, err
+// ================================
+// Conclusion
+// ================================
+
+// Conclude runs root cause extraction, solution synthesis and question
+// reframing over session.Steps, fills in the results and stores them
+// as owner's memory. Solution and reframing only need the root cause and
+// run concurrently, as do the memory writes; every stage's timing is
+// appended to session.Timings.
+func (o *Orchestrator) Conclude(
+	ctx context.Context,
+	owner types.Owner,
+	userQuestion string,
+	session *FiveWhySession,
+) error {
+
+	if len(session.Steps) == 0 {
+		return errors.New("no 5-why steps to conclude from")
 	}
+	return o.run(ctx, owner, userQuestion, session, nil, nil)
+}
 
-	session.Reframed = reframed
+// run executes the conclusion stages, after the why_tree stage when tree
+// is given.
+// Stage results are written to session under mu so checkpoints always
+// see a consistent session.
+func (o *Orchestrator) run(
+	ctx context.Context,
+	owner types.Owner,
+	userQuestion string,
+	session *FiveWhySession,
+	cp *Checkpoint,
+	tree func(ctx context.Context) (*types.WhyNode, error),
+) error {
+
+	sessionID := session.SessionID
+	var solutionText string
+
+	// memories are keyed by run, so later runs in the same session add
+	// to them instead of overwriting
+	key := session.RunID
+	if key == "" {
+		key = sessionID
+	}
 
-	// ================================
-	// MEMORY STORAGE (VECTOR DB)
-	// ================================
+	var mu sync.Mutex
+	locked := func(fn func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn()
+	}
 
-	_ = o.vector.Store(ctx, vector.Document{
-		ID:      sessionID,
-		Content: userQuestion,
-		Meta: map[string]string{
-			"type": "question",
-		},
-	})
+	// stores reject documents without an embedding
+	store := func(id, content, memoryType string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			emb, err := o.llm.Embed(ctx, content)
+			if err != nil {
+				return err
+			}
+			meta := memoryMeta(owner, memoryType)
+			meta[MetaSessionID] = sessionID
+			if session.RunID != "" {
+				meta[MetaRunID] = session.RunID
+			}
+			return o.vector.Store(ctx, vector.Document{
+				ID:      id,
+				Content: content,
+				Vector:  emb,
+				Meta:    meta,
+			})
+		}
+	}
 
-	_ = o.vector.Store(ctx, vector.Document{
-		ID:      sessionID + "_rca",
-		Content: rootCause.RootCause,
-		Meta: map[string]string{
-			"type": "root_cause",
-		},
-	})
+	// downstream stages wait for the debate's verdict when it runs
+	settled := "root_cause"
+	if o.debate.Enabled {
+		settled = "debate"
+	}
 
-	_ = o.vector.Store(ctx, vector.Document{
-		ID:      sessionID + "_solution",
-		Content: solResp.Text,
-		Meta: map[string]string{
-			"type": "solution",
+	stages := []Stage{
+		{
+			Name: "root_cause",
+			Run: func(ctx context.Context) error {
+				rc, err := o.ExtractRootCause(ctx, session.Steps)
+				if err != nil {
+					return err
+				}
+				locked(func() { session.RootCause = *rc })
+				return nil
+			},
+		},
+		{
+			Name:  "solution",
+			After: []string{settled},
+			Run: func(ctx context.Context) error {
+				var sol types.SolutionResult
+				text, err := o.synthesizeSolution(ctx, session.RootCause, session.Steps, &sol)
+				if err != nil {
+					return err
+				}
+				locked(func() { session.Solution = sol })
+				solutionText = text
+				return nil
+			},
+		},
+		{
+			Name:  "reframe",
+			After: []string{settled},
+			Run: func(ctx context.Context) error {
+				ref, err := o.ReframeQuestion(ctx, userQuestion, session.RootCause)
+				if err != nil {
+					return err
+				}
+				locked(func() { session.Reframed = *ref })
+				return nil
+			},
+		},
+		{
+			Name:       "store_question",
+			BestEffort: true,
+			Run:        store(key, userQuestion, "question"),
+		},
+		{
+			Name:       "store_root_cause",
+			After:      []string{settled},
+			BestEffort: true,
+			Run: func(ctx context.Context) error {
+				return store(key+"_rca", session.RootCause.RootCause, "root_cause")(ctx)
+			},
 		},
+		{
+			Name:       "store_solution",
+			After:      []string{"solution"},
+			BestEffort: true,
+			Run: func(ctx context.Context) error {
+				if solutionText == "" { // solution came from a checkpoint
+					b, _ := json.Marshal(session.Solution)
+					solutionText = string(b)
+				}
+				return store(key+"_solution", solutionText, "solution")(ctx)
+			},
+		},
+	}
+
+	if o.debate.Enabled {
+		stages = append(stages, Stage{
+			Name:  "debate",
+			After: []string{"root_cause"},
+			Run: func(ctx context.Context) error {
+				rc, err := o.Debate(ctx, session.Steps, session.RootCause)
+				if err != nil {
+					return err
+				}
+				locked(func() { session.RootCause = *rc })
+				return nil
+			},
+		})
+	}
+
+	if tree != nil {
+		stages[0].After = []string{"why_tree"}
+		stages = append([]Stage{{
+			Name: "why_tree",
+			Run: func(ctx context.Context) error {
+				root, err := tree(ctx)
+				if err != nil {
+					return err
+				}
+				locked(func() {
+					session.Tree = root
+					session.Steps = flattenTree(root, []types.FiveWhyStep{})
+				})
+				return nil
+			},
+		}}, stages...)
+	}
+
+	for i := range stages {
+		st := &stages[i]
+		if cp.done(st.Name) {
+			st.Skip = true
+			continue
+		}
+		if cp == nil || cp.Save == nil {
+			continue
+		}
+		run, name := st.Run, st.Name
+		st.Run = func(ctx context.Context) error {
+			if err := run(ctx); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			return cp.Save(ctx, name)
+		}
+	}
+
+	timings, err := runStages(ctx, o.workers, stages)
+	session.Timings = append(session.Timings, timings...)
+	return err
+}
+
+func (o *Orchestrator) synthesizeSolution(
+	ctx context.Context,
+	rc types.RootCauseResult,
+	steps []types.FiveWhyStep,
+	out *types.SolutionResult,
+) (string, error) {
+
+	solResp, err := o.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModePlanning,
+		Prompt: o.prompt.BuildSolutionPrompt(rc, steps),
 	})
+	if err != nil {
+		return "", err
+	}
 
-	return session, nil
+	if err := o.prompt.ParseSolution(solResp.Text, out); err != nil {
+		return "", err
+	}
+	return solResp.Text, nil
 }
 
 // ================================
@@ -232,6 +577,7 @@ func (o *Orchestrator) ExtractRootCause(
 	if err := o.prompt.ParseRootCause(rcaResp.Text, &rootCause); err != nil {
 		return nil, err
 	}
+	groundRootCause(&rootCause, steps)
 
 	return &rootCause, nil
 }
@@ -259,3 +605,31 @@ func (o *Orchestrator) ReframeQuestion(
 
 	return &reframed, nil
 }
+
+// ================================
+// Helpers
+// ================================
+
+// flattenTree appends the tree's steps in pre-order.
+func flattenTree(node *types.WhyNode, steps []types.FiveWhyStep) []types.FiveWhyStep {
+	if node == nil {
+		return steps
+	}
+	steps = append(steps, node.Step)
+	for _, child := range node.Children {
+		steps = flattenTree(child, steps)
+	}
+	return steps
+}
+
+// Meta keys linking a 5-Why memory back to its session and run.
+const (
+	MetaSessionID = "session_id"
+	MetaRunID     = "run_id"
+)
+
+func memoryMeta(owner types.Owner, memoryType string) map[string]interface{} {
+	meta := owner.Meta()
+	meta["type"] = memoryType
+	return meta
+}
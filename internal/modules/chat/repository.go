@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // ================================
@@ -16,6 +18,14 @@ type Repository interface {
 	SaveMessage(ctx context.Context, sessionID, userID, userMsg, aiMsg string) error
 	SaveFiveWhySession(ctx context.Context, userID string, session *FiveWhySession) error
 	GetSessionHistory(ctx context.Context, sessionID string, limit int) ([]ChatRecord, error)
+	PurgeUser(ctx context.Context, userID string) (map[string]int64, error)
+
+	CreateFiveWhyRun(ctx context.Context, run *FiveWhyRun) error
+	GetFiveWhyRun(ctx context.Context, id string) (*FiveWhyRun, error)
+	UpdateFiveWhyRun(ctx context.Context, run *FiveWhyRun) error
+	FinalizedFiveWhyRuns(ctx context.Context, tenantID, userID string, ids []string) (map[string]*FiveWhyRun, error)
+
+	SaveAnalysis(ctx context.Context, report *AnalysisReport) error
 }
 
 // ================================
@@ -68,6 +78,45 @@ func (r *PostgresRepository) Init(ctx context.Context) error {
 			reframed JSONB,
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		);`,
+
+		`ALTER TABLE fivewhy_sessions ADD COLUMN IF NOT EXISTS tree JSONB;`,
+
+		`CREATE TABLE IF NOT EXISTS fivewhy_runs (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			question TEXT NOT NULL,
+			status TEXT NOT NULL,
+			level INT NOT NULL,
+			current_question TEXT NOT NULL DEFAULT '',
+			steps JSONB NOT NULL DEFAULT '[]',
+			result JSONB,
+			version INT NOT NULL DEFAULT 1,
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS fivewhy_runs_user_idx ON fivewhy_runs (user_id);`,
+
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'interactive';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS completed_stages JSONB NOT NULL DEFAULT '[]';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS failed_stage TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE fivewhy_runs ADD COLUMN IF NOT EXISTS error TEXT NOT NULL DEFAULT '';`,
+
+		`CREATE TABLE IF NOT EXISTS analyses (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT '',
+			session_id TEXT NOT NULL DEFAULT '',
+			method TEXT NOT NULL,
+			problem TEXT NOT NULL,
+			summary TEXT NOT NULL DEFAULT '',
+			result JSONB NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS analyses_user_idx ON analyses (user_id);`,
 	}
 
 	for _, q := range queries {
@@ -112,9 +161,15 @@ func (r *PostgresRepository) SaveFiveWhySession(ctx context.Context, userID stri
 	solJSON, _ := json.Marshal(session.Solution)
 	refJSON, _ := json.Marshal(session.Reframed)
 
+	var treeJSON interface{} // NULL for runs without a tree
+	if session.Tree != nil {
+		b, _ := json.Marshal(session.Tree)
+		treeJSON = string(b)
+	}
+
 	query := `INSERT INTO fivewhy_sessions
-		(id, user_id, session_id, steps, root_cause, solution, reframed)
-		VALUES ($1,$2,$3,$4,$5,$6,$7);`
+		(id, user_id, session_id, steps, root_cause, solution, reframed, tree)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8);`
 
 	_, err := r.db.ExecContext(ctx, query,
 		id,
@@ -124,6 +179,7 @@ func (r *PostgresRepository) SaveFiveWhySession(ctx context.Context, userID stri
 		rcJSON,
 		solJSON,
 		refJSON,
+		treeJSON,
 	)
 
 	return err
@@ -174,6 +230,252 @@ func (r *PostgresRepository) GetSessionHistory(ctx context.Context, sessionID st
 	return records, nil
 }
 
+// ================================
+// Purge User
+// ================================
+
+// userTables hold rows keyed by user_id that a profile deletion erases.
+var userTables = []string{"chat_messages", "fivewhy_sessions", "fivewhy_runs", "analyses"}
+
+// PurgeUser deletes all of the user's rows in one transaction and
+// returns how many were removed per table.
+func (r *PostgresRepository) PurgeUser(ctx context.Context, userID string) (map[string]int64, error) {
+	if userID == "" {
+		return nil, errors.New("missing user id")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deleted := map[string]int64{}
+	for _, table := range userTables {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = $1;`, userID)
+		if err != nil {
+			return nil, err
+		}
+		deleted[table], _ = res.RowsAffected()
+	}
+
+	return deleted, tx.Commit()
+}
+
+// ================================
+// 5-Why Runs
+// ================================
+
+func (r *PostgresRepository) CreateFiveWhyRun(ctx context.Context, run *FiveWhyRun) error {
+	stepsJSON, _ := json.Marshal(run.Steps)
+	stagesJSON, _ := json.Marshal(append([]string{}, run.CompletedStages...)) // never null
+
+	query := `INSERT INTO fivewhy_runs
+		(id, user_id, tenant_id, session_id, question, mode, status, level, current_question,
+		 steps, result, completed_stages, failed_stage, error, version, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17);`
+
+	_, err := r.db.ExecContext(ctx, query,
+		run.ID,
+		run.UserID,
+		run.TenantID,
+		run.SessionID,
+		run.Question,
+		run.Mode,
+		run.Status,
+		run.Level,
+		run.CurrentQuestion,
+		stepsJSON,
+		runResultJSON(run),
+		stagesJSON,
+		run.FailedStage,
+		run.Error,
+		run.Version,
+		run.CreatedAt,
+		run.UpdatedAt,
+	)
+	return err
+}
+
+// runColumns is the column list scanRun reads, in order.
+const runColumns = `id, user_id, tenant_id, session_id, question, mode, status, level,
+	current_question, steps, result, completed_stages, failed_stage, error,
+	version, created_at, updated_at`
+
+func (r *PostgresRepository) GetFiveWhyRun(ctx context.Context, id string) (*FiveWhyRun, error) {
+	query := `SELECT ` + runColumns + `
+		FROM fivewhy_runs
+		WHERE id = $1;`
+
+	run, err := scanRun(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRunNotFound
+	}
+	return run, err
+}
+
+// FinalizedFiveWhyRuns loads the finalized runs among ids within
+// tenantID, keyed by run ID; a non-empty userID narrows it to that
+// user's runs. Other runs are left out.
+func (r *PostgresRepository) FinalizedFiveWhyRuns(ctx context.Context, tenantID, userID string, ids []string) (map[string]*FiveWhyRun, error) {
+	runs := make(map[string]*FiveWhyRun)
+	if len(ids) == 0 {
+		return runs, nil
+	}
+
+	query := `SELECT ` + runColumns + `
+		FROM fivewhy_runs
+		WHERE id = ANY($1) AND status = $2
+			AND tenant_id = $3 AND ($4 = '' OR user_id = $4);`
+
+	rows, err := r.db.QueryContext(ctx, query, pq.Array(ids), RunFinalized, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs[run.ID] = run
+	}
+	return runs, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRun(row rowScanner) (*FiveWhyRun, error) {
+	var run FiveWhyRun
+	var stepsJSON, resultJSON, stagesJSON []byte
+
+	err := row.Scan(
+		&run.ID,
+		&run.UserID,
+		&run.TenantID,
+		&run.SessionID,
+		&run.Question,
+		&run.Mode,
+		&run.Status,
+		&run.Level,
+		&run.CurrentQuestion,
+		&stepsJSON,
+		&resultJSON,
+		&stagesJSON,
+		&run.FailedStage,
+		&run.Error,
+		&run.Version,
+		&run.CreatedAt,
+		&run.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(stepsJSON, &run.Steps); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(stagesJSON, &run.CompletedStages); err != nil {
+		return nil, err
+	}
+	if len(resultJSON) > 0 {
+		run.Result = &FiveWhySession{}
+		if err := json.Unmarshal(resultJSON, run.Result); err != nil {
+			return nil, err
+		}
+	}
+
+	return &run, nil
+}
+
+// UpdateFiveWhyRun saves run if nobody else changed it since it was
+// read (optimistic locking on Version), then bumps run.Version.
+func (r *PostgresRepository) UpdateFiveWhyRun(ctx context.Context, run *FiveWhyRun) error {
+	stepsJSON, _ := json.Marshal(run.Steps)
+	stagesJSON, _ := json.Marshal(append([]string{}, run.CompletedStages...)) // never null
+
+	query := `UPDATE fivewhy_runs SET
+			status = $1,
+			level = $2,
+			current_question = $3,
+			steps = $4,
+			result = $5,
+			completed_stages = $6,
+			failed_stage = $7,
+			error = $8,
+			version = version + 1,
+			updated_at = $9
+		WHERE id = $10 AND version = $11;`
+
+	res, err := r.db.ExecContext(ctx, query,
+		run.Status,
+		run.Level,
+		run.CurrentQuestion,
+		stepsJSON,
+		runResultJSON(run),
+		stagesJSON,
+		run.FailedStage,
+		run.Error,
+		run.UpdatedAt,
+		run.ID,
+		run.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRunConflict
+	}
+
+	run.Version++
+	return nil
+}
+
+// runResultJSON is NULL until the run has a (possibly partial) result.
+func runResultJSON(run *FiveWhyRun) interface{} {
+	if run.Result == nil {
+		return nil
+	}
+	b, _ := json.Marshal(run.Result)
+	return string(b)
+}
+
+// ================================
+// Analyses
+// ================================
+
+func (r *PostgresRepository) SaveAnalysis(ctx context.Context, report *AnalysisReport) error {
+	resultJSON, err := json.Marshal(report.Result)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO analyses
+		(id, user_id, tenant_id, session_id, method, problem, summary, result, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9);`
+
+	_, err = r.db.ExecContext(ctx, query,
+		report.ID,
+		report.UserID,
+		report.TenantID,
+		report.SessionID,
+		report.Method,
+		report.Problem,
+		report.Summary,
+		string(resultJSON),
+		report.CreatedAt,
+	)
+	return err
+}
+
 // ================================
 // Helpers
 // ================================
@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"quavixAI/internal/modules/llm"
+	"quavixAI/internal/modules/prompt"
+	"quavixAI/internal/modules/vector"
+)
+
+// ================================
+// Reranker Interface
+// ================================
+
+// Reranker reorders recalled candidates by relevance to the query.
+// Implementations set Document.Score to their own relevance score.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, docs []vector.Document) ([]vector.Document, error)
+}
+
+// ================================
+// Lexical Overlap Reranker
+// ================================
+
+// LexicalReranker scores each document by the share of distinct query
+// terms it contains. Ties keep the retrieval order. No LLM calls.
+type LexicalReranker struct{}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{}
+}
+
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, docs []vector.Document) ([]vector.Document, error) {
+	qterms := map[string]bool{}
+	for _, t := range vector.Tokenize(query) {
+		qterms[t] = true
+	}
+	if len(qterms) == 0 {
+		return docs, nil
+	}
+
+	out := make([]vector.Document, len(docs))
+	for i, d := range docs {
+		matched := map[string]bool{}
+		for _, t := range vector.Tokenize(d.Content) {
+			if qterms[t] {
+				matched[t] = true
+			}
+		}
+		d.Score = float64(len(matched)) / float64(len(qterms))
+		out[i] = d
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
+
+// ================================
+// LLM Reranker
+// ================================
+
+// DefaultLLMRerankCandidates caps how many candidates one rerank call
+// shows the model.
+const DefaultLLMRerankCandidates = 20
+
+// LLMReranker asks the model to rate the candidates against the query
+// on a 0-10 scale, all in one call, and normalizes the ratings into
+// Score. Candidates past the cap are not shown and rank after the rated
+// ones with a score of 0. If the call fails the whole list is handed to
+// the fallback so scores stay comparable.
+type LLMReranker struct {
+	llm           *llm.Manager
+	prompt        prompt.Builder
+	fallback      Reranker
+	maxCandidates int
+}
+
+func NewLLMReranker(llmMgr *llm.Manager, pb prompt.Builder, fallback Reranker) *LLMReranker {
+	if fallback == nil {
+		fallback = NewLexicalReranker()
+	}
+	return &LLMReranker{
+		llm:           llmMgr,
+		prompt:        pb,
+		fallback:      fallback,
+		maxCandidates: DefaultLLMRerankCandidates,
+	}
+}
+
+func (r *LLMReranker) Rerank(ctx context.Context, query string, docs []vector.Document) ([]vector.Document, error) {
+	if r.llm == nil {
+		return r.fallback.Rerank(ctx, query, docs)
+	}
+
+	rated := docs
+	if len(rated) > r.maxCandidates {
+		rated = rated[:r.maxCandidates]
+	}
+	contents := make([]string, len(rated))
+	for i, d := range rated {
+		contents[i] = snippet(d.Content)
+	}
+
+	resp, err := r.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: r.prompt.BuildRerankPrompt(query, contents),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return r.fallback.Rerank(ctx, query, docs)
+	}
+
+	scores, err := r.prompt.ParseRerankScores(resp.Text, len(rated))
+	if err != nil {
+		return r.fallback.Rerank(ctx, query, docs)
+	}
+
+	out := make([]vector.Document, len(docs))
+	for i, d := range docs {
+		d.Score = 0
+		if i < len(scores) {
+			d.Score = scores[i] / 10
+		}
+		out[i] = d
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out, nil
+}
@@ -3,12 +3,17 @@ package chat
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"quavixAI/internal/modules/llm"
 	"quavixAI/internal/modules/prompt"
 	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/user"
 	"quavixAI/internal/modules/vector"
+
+	"github.com/google/uuid"
 )
 
 // ================================
@@ -21,6 +26,20 @@ type ServiceConfig struct {
 	Vector vector.Store
 	Memory *MemoryEngine
 
+	Depth        DepthConfig  // adaptive 5-Why bounds
+	Debate       DebateConfig // critic/proposer/judge check of root causes
+	StageWorkers int          // concurrent orchestrator stages
+
+	// Evidence is what 5-Why answers cite; Source defaults to Memory
+	Evidence EvidenceConfig
+
+	// Similar looks up earlier investigations before each 5-Why
+	Similar SimilarConfig
+
+	// Methods are registered next to the built-in 5-Why, fishbone,
+	// fault tree and A3 methodologies; a same-named one replaces them.
+	Methods []Methodology
+
 	FiveWhy   bool
 	Evaluator bool
 	RootCause bool
@@ -37,17 +56,48 @@ type Service struct {
 	vector       vector.Store
 	memory       *MemoryEngine
 	orchestrator *Orchestrator
+	methods      *MethodRegistry
 
 	cfg ServiceConfig
 }
 
 func NewService(cfg ServiceConfig) *Service {
+	cfg.Similar.defaults()
+	builder := prompt.NewBuilder()
+
+	evidence := cfg.Evidence
+	if evidence.Source == nil && cfg.Memory != nil {
+		evidence.Source = cfg.Memory
+	}
+
+	orchestrator := NewOrchestrator(OrchestratorConfig{
+		LLM:      cfg.LLM,
+		Vector:   cfg.Vector,
+		Prompt:   builder,
+		Depth:    cfg.Depth,
+		Debate:   cfg.Debate,
+		Evidence: evidence,
+		Workers:  cfg.StageWorkers,
+	})
+
+	method := MethodConfig{LLM: cfg.LLM, Prompt: builder, Workers: cfg.StageWorkers}
+	methods := NewMethodRegistry(
+		orchestrator,
+		NewFishbone(method),
+		NewFaultTree(method),
+		NewA3(method),
+	)
+	for _, m := range cfg.Methods {
+		methods.Register(m)
+	}
+
 	return &Service{
 		repo:         cfg.Repo,
 		llm:          cfg.LLM,
 		vector:       cfg.Vector,
 		memory:       cfg.Memory,
-		orchestrator: NewOrchestrator(cfg.LLM, cfg.Vector, prompt.NewBuilder()),
+		orchestrator: orchestrator,
+		methods:      methods,
 		cfg:          cfg,
 	}
 }
@@ -57,20 +107,26 @@ func NewService(cfg ServiceConfig) *Service {
 // ================================
 
 // Standard chat (memory-augmented reasoning)
-func (s *Service) Chat(ctx context.Context, sessionID, userID, message string) (*llm.Response, error) {
+func (s *Service) Chat(ctx context.Context, sessionID string, owner types.Owner, message string) (*llm.Response, error) {
 	if message == "" {
 		return nil, errors.New("empty message")
 	}
+	ctx = types.WithOwner(ctx, owner)
 
 	// store in session memory
 	if s.memory != nil {
-		_ = s.memory.AppendSession(ctx, sessionID, "user", message)
+		if err := s.memory.AppendSession(ctx, owner, sessionID, "user", message); err != nil {
+			return nil, err
+		}
 	}
 
 	// hybrid context
 	contextStr := ""
 	if s.memory != nil {
-		ctxData, _ := s.memory.HybridContext(ctx, sessionID, message, 5)
+		ctxData, err := s.memory.HybridContext(ctx, owner, sessionID, message, 5)
+		if err != nil {
+			return nil, err
+		}
 		contextStr = ctxData
 	}
 
@@ -86,12 +142,14 @@ func (s *Service) Chat(ctx context.Context, sessionID, userID, message string) (
 
 	// store AI response
 	if s.memory != nil {
-		_ = s.memory.AppendSession(ctx, sessionID, "assistant", resp.Text)
+		if err := s.memory.AppendSession(ctx, owner, sessionID, "assistant", resp.Text); err != nil {
+			return nil, err
+		}
 	}
 
 	// persist conversation
 	if s.repo != nil {
-		_ = s.repo.SaveMessage(ctx, sessionID, userID, message, resp.Text)
+		_ = s.repo.SaveMessage(ctx, sessionID, owner.UserID, message, resp.Text)
 	}
 
 	return &resp, nil
@@ -101,44 +159,97 @@ func (s *Service) Chat(ctx context.Context, sessionID, userID, message string) (
 // 5-Why Reasoning Pipeline
 // ================================
 
-func (s *Service) FiveWhy(ctx context.Context, sessionID, userID, question string) (*FiveWhySession, error) {
+// FiveWhy first looks up similar earlier incidents: they come back with
+// the result and, with opts.Reuse, a near duplicate's analysis is
+// returned without running the pipeline.
+func (s *Service) FiveWhy(ctx context.Context, sessionID string, owner types.Owner, question string, opts FiveWhyOptions) (*FiveWhySession, error) {
 	if !s.cfg.FiveWhy {
 		return nil, errors.New("five-why engine disabled")
 	}
+	if question == "" {
+		return nil, errors.New("empty question")
+	}
+	ctx = types.WithOwner(ctx, owner)
 
 	// store question
 	if s.memory != nil {
-		_ = s.memory.AppendSession(ctx, sessionID, "user", question)
+		if err := s.memory.AppendSession(ctx, owner, sessionID, "user", question); err != nil {
+			return nil, err
+		}
 	}
 
-	session, err := s.orchestrator.RunFiveWhy(ctx, sessionID, question)
+	// advisory: a failed lookup only costs the fast path
+	similar, runs, err := s.similarIncidents(ctx, owner, question)
 	if err != nil {
-		return nil, err
+		log.Printf("session %s: similar incidents lookup failed: %v", sessionID, err)
+	}
+	if opts.Reuse {
+		if prior := s.reusable(similar, runs); prior != nil {
+			return s.reuseFiveWhy(ctx, sessionID, owner, prior, similar)
+		}
 	}
 
-	// persist root cause
-	if s.vector != nil {
-		_ = s.vector.Store(ctx, vector.Document{
-			ID:      sessionID + "_root",
-			Content: session.RootCause.RootCause,
-			Meta: map[string]string{
-				"type":   "root_cause",
-				"userID": userID,
-			},
-		})
+	// every completed stage is checkpointed under the run, so a failure
+	// can be resumed instead of starting over
+	now := time.Now()
+	run := &FiveWhyRun{
+		ID:        uuid.NewString(),
+		SessionID: sessionID,
+		UserID:    owner.UserID,
+		TenantID:  owner.TenantID,
+		Question:  question,
+		Mode:      RunAuto,
+		Status:    RunRunning,
+		Steps:     []types.FiveWhyStep{},
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
+	run.Result = newRunSession(run)
+	run.Result.SimilarIncidents = similar
+
+	if s.repo != nil {
+		if err := s.repo.CreateFiveWhyRun(ctx, run); err != nil {
+			return nil, err
+		}
+		if opts.OnRun != nil {
+			opts.OnRun(run.ID)
+		}
+	} else {
+		run.Result.RunID = "" // nothing to resume from
+	}
+
+	return s.executeRun(ctx, owner, run)
+}
+
+// finishFiveWhy records a concluded investigation: session history,
+// graph extraction and the full session row. The root cause memory is
+// written by the pipeline's store_root_cause stage, keyed by run.
+func (s *Service) finishFiveWhy(ctx context.Context, owner types.Owner, question string, session *FiveWhySession) error {
+	sessionID := session.SessionID
 
 	// store memory
 	if s.memory != nil {
-		_ = s.memory.AppendSession(ctx, sessionID, "assistant", session.RootCause.RootCause)
+		if err := s.memory.AppendSession(ctx, owner, sessionID, "assistant", session.RootCause.RootCause); err != nil {
+			return err
+		}
+	}
+
+	// entities and cause -> effect relations, off the request path
+	if s.memory != nil && s.memory.Graph() != nil {
+		go func(ctx context.Context) {
+			if err := s.memory.ExtractGraph(ctx, owner, question, session); err != nil {
+				log.Printf("session %s: graph extraction failed: %v", sessionID, err)
+			}
+		}(context.WithoutCancel(ctx))
 	}
 
 	// persist full session
 	if s.repo != nil {
-		_ = s.repo.SaveFiveWhySession(ctx, userID, session)
+		_ = s.repo.SaveFiveWhySession(ctx, owner.UserID, session)
 	}
 
-	return session, nil
+	return nil
 }
 
 // ================================
@@ -150,7 +261,11 @@ func (s *Service) RootCause(ctx context.Context, steps []types.FiveWhyStep) (*ty
 		return nil, errors.New("root-cause engine disabled")
 	}
 
-	return s.orchestrator.ExtractRootCause(ctx, steps)
+	rc, err := s.orchestrator.ExtractRootCause(ctx, steps)
+	if err != nil || !s.cfg.Debate.Enabled {
+		return rc, err
+	}
+	return s.orchestrator.Debate(ctx, steps, *rc)
 }
 
 // ================================
@@ -169,27 +284,46 @@ func (s *Service) Reframe(ctx context.Context, question string, rc types.RootCau
 // Memory APIs
 // ================================
 
-func (s *Service) CompressSession(ctx context.Context, sessionID string) (string, error) {
+func (s *Service) CompressSession(ctx context.Context, owner types.Owner, sessionID string) (string, error) {
 	if s.memory == nil {
 		return "", errors.New("memory engine not configured")
 	}
-	return s.memory.CompressSession(ctx, sessionID)
+	return s.memory.CompressSession(types.WithOwner(ctx, owner), owner, sessionID)
+}
+
+func (s *Service) GetSession(ctx context.Context, owner types.Owner, sessionID string, page SessionPage) (*SessionMemory, error) {
+	if s.memory == nil {
+		return nil, errors.New("memory engine not configured")
+	}
+	return s.memory.GetSession(ctx, owner, sessionID, page)
+}
+
+// EntityGraph returns one entity's causes and effects, or the most
+// mentioned entities when entity is empty.
+func (s *Service) EntityGraph(ctx context.Context, owner types.Owner, entity string, limit int) (interface{}, error) {
+	if s.memory == nil || s.memory.Graph() == nil {
+		return nil, errors.New("memory graph not configured")
+	}
+	if entity == "" {
+		return s.memory.Graph().Top(ctx, owner, limit)
+	}
+	return s.memory.Graph().Entity(ctx, owner, entity, limit)
 }
 
-func (s *Service) Recall(ctx context.Context, query string, limit int) (*RetrievedMemory, error) {
+func (s *Service) Recall(ctx context.Context, query string, opts RecallOptions) (*RetrievedMemory, error) {
 	if s.memory == nil {
 		return nil, errors.New("memory engine not configured")
 	}
-	return s.memory.Recall(ctx, query, limit)
+	return s.memory.Recall(ctx, query, opts)
 }
 
 // ================================
 // Maintenance Jobs
 // ================================
 
-func (s *Service) BackgroundCompression(ctx context.Context, sessionID string) {
+func (s *Service) BackgroundCompression(ctx context.Context, owner types.Owner, sessionID string) {
 	go func() {
-		_, _ = s.memory.CompressSession(ctx, sessionID)
+		_, _ = s.memory.CompressSession(types.WithOwner(ctx, owner), owner, sessionID)
 	}()
 }
 
@@ -198,3 +332,64 @@ func (s *Service) CleanupSession(ctx context.Context, sessionID string) {
 		time.Sleep(1 * time.Second)
 	}()
 }
+
+// ================================
+// Right to be Forgotten
+// ================================
+
+// Purge deletes everything the chat module holds for userID: Redis
+// sessions, chat and 5-Why rows, vector documents and graph mentions.
+// Every store is attempted; the results of those that succeeded are
+// returned along with the joined errors of those that did not.
+func (s *Service) Purge(ctx context.Context, userID string) ([]user.PurgeResult, error) {
+	if userID == "" {
+		return nil, errors.New("missing user id")
+	}
+
+	var results []user.PurgeResult
+	var errs []error
+
+	record := func(store string, n int64, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", store, err))
+			return
+		}
+		results = append(results, user.PurgeResult{Store: store, Deleted: n})
+	}
+
+	if s.memory != nil {
+		n, err := s.memory.PurgeSessions(ctx, userID)
+		record("redis_sessions", n, err)
+
+		if g := s.memory.Graph(); g != nil {
+			n, err := g.Purge(ctx, userID)
+			record("memory_graph", n, err)
+		}
+	}
+
+	if s.llm != nil {
+		n, err := s.llm.ForgetUser(ctx, userID)
+		record("llm_short_term", n, err)
+	}
+
+	if s.repo != nil {
+		deleted, err := s.repo.PurgeUser(ctx, userID)
+		if err != nil {
+			record("postgres", 0, err)
+		}
+		for _, table := range userTables {
+			if n, ok := deleted[table]; ok {
+				record(table, n, nil)
+			}
+		}
+	}
+
+	if s.vector != nil {
+		n, err := s.vector.DeleteWhere(ctx, vector.Filter{
+			Equals: map[string]interface{}{types.MetaUserID: userID},
+		})
+		record("vector_documents", n, err)
+	}
+
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+)
+
+// ================================
+// Similar Incidents
+// ================================
+
+const (
+	// DefaultSimilarMinScore is how close (vector.Score) an earlier
+	// question or root cause must be to count as a similar incident.
+	DefaultSimilarMinScore = 0.8
+
+	// DefaultReuseMinScore gates the reuse fast path: only a near
+	// duplicate of an earlier incident skips the pipeline.
+	DefaultReuseMinScore = 0.9
+
+	DefaultSimilarLimit = 3
+)
+
+// SimilarConfig tunes the lookup of earlier investigations that runs
+// before every 5-Why.
+type SimilarConfig struct {
+	Disabled      bool
+	MinScore      float64
+	ReuseMinScore float64
+	Limit         int
+}
+
+func (c *SimilarConfig) defaults() {
+	if c.MinScore <= 0 {
+		c.MinScore = DefaultSimilarMinScore
+	}
+	if c.ReuseMinScore <= 0 {
+		c.ReuseMinScore = DefaultReuseMinScore
+	}
+	if c.Limit <= 0 {
+		c.Limit = DefaultSimilarLimit
+	}
+}
+
+// SimilarIncident is an earlier investigation close to a new question.
+// RootCause and Solution come from its finalized run; without one
+// (an analysis, or a run that never finished) only the matched memory
+// is known.
+type SimilarIncident struct {
+	SessionID  string                 `json:"session_id"`
+	RunID      string                 `json:"run_id,omitempty"`
+	Score      float64                `json:"score"`
+	MatchedOn  string                 `json:"matched_on"` // question or root_cause
+	Question   string                 `json:"question,omitempty"`
+	RootCause  *types.RootCauseResult `json:"root_cause,omitempty"`
+	Solution   *types.SolutionResult  `json:"solution,omitempty"`
+	ResolvedAt *time.Time             `json:"resolved_at,omitempty"`
+}
+
+// FiveWhyOptions are per-request switches for Service.FiveWhy.
+type FiveWhyOptions struct {
+	// Reuse returns the best similar incident's analysis instead of
+	// running the pipeline, when it scores at least ReuseMinScore.
+	Reuse bool
+
+	// OnRun is called with the run's ID once it is stored, before any
+	// stage executes.
+	OnRun func(runID string)
+}
+
+// incidentSuffixes map the IDs of an investigation's memories back to
+// the run or analysis they are keyed by.
+var incidentSuffixes = []string{"_rca", "_solution"}
+
+func incidentKey(docID string) string {
+	for _, suffix := range incidentSuffixes {
+		if strings.HasSuffix(docID, suffix) {
+			return strings.TrimSuffix(docID, suffix)
+		}
+	}
+	return docID
+}
+
+// SimilarIncidents finds earlier investigations of question within the
+// owner's tenant (or the owner's own, without a tenant), best first.
+func (s *Service) SimilarIncidents(ctx context.Context, owner types.Owner, question string) ([]SimilarIncident, error) {
+	incidents, _, err := s.similarIncidents(ctx, owner, question)
+	return incidents, err
+}
+
+func (s *Service) similarIncidents(ctx context.Context, owner types.Owner, question string) ([]SimilarIncident, map[string]*FiveWhyRun, error) {
+	cfg := s.cfg.Similar
+	if cfg.Disabled || s.vector == nil || question == "" {
+		return nil, nil, nil
+	}
+
+	emb, err := s.llm.Embed(ctx, question)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// several memories of one incident can match, so over-fetch
+	search := vector.SearchOptions{Limit: cfg.Limit * 4, MinScore: cfg.MinScore}
+	search.In = map[string][]interface{}{"type": {"question", "root_cause"}}
+	userID := ""
+	if owner.TenantID != "" {
+		search.Equals = map[string]interface{}{types.MetaTenantID: owner.TenantID}
+	} else {
+		userID = owner.UserID
+		search.Equals = map[string]interface{}{types.MetaUserID: owner.UserID}
+	}
+
+	docs, err := s.vector.Search(ctx, emb, search)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].Score > docs[j].Score
+	})
+
+	var incidents []SimilarIncident
+	seen := make(map[string]bool)
+	for _, d := range docs {
+		key := incidentKey(d.ID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		inc := SimilarIncident{SessionID: key, Score: d.Score}
+		if id, ok := d.Meta[MetaSessionID].(string); ok && id != "" {
+			inc.SessionID = id
+		}
+		inc.RunID, _ = d.Meta[MetaRunID].(string)
+		inc.MatchedOn, _ = d.Meta["type"].(string)
+		if inc.MatchedOn == "question" {
+			inc.Question = d.Content
+		} else {
+			inc.RootCause = &types.RootCauseResult{RootCause: d.Content}
+		}
+		incidents = append(incidents, inc)
+
+		if len(incidents) == cfg.Limit {
+			break
+		}
+	}
+	if len(incidents) == 0 || s.repo == nil {
+		return incidents, nil, nil
+	}
+
+	var runIDs []string
+	for _, inc := range incidents {
+		if inc.RunID != "" {
+			runIDs = append(runIDs, inc.RunID)
+		}
+	}
+	runs, err := s.repo.FinalizedFiveWhyRuns(ctx, owner.TenantID, userID, runIDs)
+	if err != nil {
+		// the matched memories are still worth showing
+		log.Printf("similar incidents: loading runs failed: %v", err)
+		return incidents, nil, nil
+	}
+
+	for i := range incidents {
+		run := runs[incidents[i].RunID]
+		if run == nil || run.Result == nil {
+			continue
+		}
+		rc, sol, resolved := run.Result.RootCause, run.Result.Solution, run.UpdatedAt
+
+		incidents[i].Question = run.Question
+		incidents[i].RootCause = &rc
+		incidents[i].Solution = &sol
+		incidents[i].ResolvedAt = &resolved
+	}
+
+	return incidents, runs, nil
+}
+
+// reusable is the run behind the best incident, if it is close enough
+// to stand in for a new analysis.
+func (s *Service) reusable(incidents []SimilarIncident, runs map[string]*FiveWhyRun) *FiveWhyRun {
+	if len(incidents) == 0 || incidents[0].Score < s.cfg.Similar.ReuseMinScore {
+		return nil
+	}
+	run := runs[incidents[0].RunID]
+	if run == nil || run.Result == nil || run.Result.RootCause.RootCause == "" {
+		return nil
+	}
+	return run
+}
+
+// reuseFiveWhy answers question with prior's analysis, recorded under
+// sessionID, instead of running the pipeline.
+func (s *Service) reuseFiveWhy(ctx context.Context, sessionID string, owner types.Owner, prior *FiveWhyRun, similar []SimilarIncident) (*FiveWhySession, error) {
+	session := *prior.Result
+	session.SessionID = sessionID
+	session.RunID = ""
+	session.Timings = nil
+	session.ReusedFrom = prior.ID
+	session.SimilarIncidents = similar
+	session.CreatedAt = time.Now()
+
+	if s.memory != nil {
+		if err := s.memory.AppendSession(ctx, owner, sessionID, "assistant", session.RootCause.RootCause); err != nil {
+			return nil, err
+		}
+	}
+	if s.repo != nil {
+		_ = s.repo.SaveFiveWhySession(ctx, owner.UserID, &session)
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,193 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ================================
+// Stage DAG Executor
+// ================================
+
+// DefaultStageWorkers caps how many pipeline stages run at once.
+const DefaultStageWorkers = 4
+
+// Stage is one node of a pipeline DAG. It starts once every stage named
+// in After has succeeded. A failing BestEffort stage is logged and
+// recorded in its timing but does not stop the pipeline. A Skip stage
+// (already done by an earlier attempt) counts as succeeded without running.
+type Stage struct {
+	Name       string
+	After      []string
+	BestEffort bool
+	Skip       bool
+	Run        func(ctx context.Context) error
+}
+
+// StageError reports which stage failed a pipeline.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (e *StageError) Error() string { return e.Stage + ": " + e.Err.Error() }
+func (e *StageError) Unwrap() error { return e.Err }
+
+// StageTiming profiles one stage of a run.
+type StageTiming struct {
+	Stage      string    `json:"stage"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type stageDone struct {
+	index int
+	err   error
+}
+
+// runStages executes stages in dependency order, independent ones
+// concurrently on at most workers goroutines. The first failure cancels
+// the stages still running and is returned as a *StageError; timings
+// cover every stage that ran.
+func runStages(ctx context.Context, workers int, stages []Stage) ([]StageTiming, error) {
+	if workers <= 0 {
+		workers = DefaultStageWorkers
+	}
+
+	index := make(map[string]int, len(stages))
+	for i, st := range stages {
+		if _, dup := index[st.Name]; dup {
+			return nil, fmt.Errorf("duplicate stage %q", st.Name)
+		}
+		index[st.Name] = i
+	}
+
+	pending := make([]int, len(stages)) // unfinished dependencies
+	dependents := make([][]int, len(stages))
+	for i, st := range stages {
+		for _, dep := range st.After {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("stage %q depends on unknown stage %q", st.Name, dep)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	if err := checkAcyclic(pending, dependents); err != nil {
+		return nil, err
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(workers)
+
+	timings := make([]StageTiming, len(stages))
+	started := make([]bool, len(stages))
+	done := make(chan stageDone, len(stages))
+
+	launch := func(i int) {
+		if stages[i].Skip {
+			done <- stageDone{index: i}
+			return
+		}
+		started[i] = true
+		g.Go(func() error {
+			st := stages[i]
+			start := time.Now()
+
+			var err error
+			if gctx.Err() != nil {
+				err = gctx.Err()
+			} else {
+				err = st.Run(gctx)
+			}
+
+			timings[i] = StageTiming{
+				Stage:      st.Name,
+				StartedAt:  start,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				timings[i].Error = err.Error()
+				if st.BestEffort {
+					log.Printf("stage %s failed (best effort): %v", st.Name, err)
+					err = nil
+				}
+			}
+
+			done <- stageDone{index: i, err: err}
+			if err != nil {
+				return &StageError{Stage: st.Name, Err: err}
+			}
+			return nil
+		})
+	}
+
+	for i := range stages {
+		if pending[i] == 0 {
+			launch(i)
+		}
+	}
+
+	// done is buffered for every stage, so workers never block on it
+	// while launch waits for a free worker
+	for finished := 0; finished < len(stages); finished++ {
+		d := <-done
+		if d.err != nil {
+			break
+		}
+		for _, j := range dependents[d.index] {
+			pending[j]--
+			if pending[j] == 0 {
+				launch(j)
+			}
+		}
+	}
+
+	err := g.Wait()
+
+	out := make([]StageTiming, 0, len(stages))
+	for i, t := range timings {
+		if started[i] && t.Stage != "" {
+			out = append(out, t)
+		}
+	}
+	return out, err
+}
+
+// checkAcyclic runs Kahn's algorithm on a copy of the dependency counts.
+func checkAcyclic(pending []int, dependents [][]int) error {
+	left := append([]int(nil), pending...)
+
+	var ready []int
+	for i, n := range left {
+		if n == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	seen := 0
+	for len(ready) > 0 {
+		i := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		seen++
+		for _, j := range dependents[i] {
+			left[j]--
+			if left[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	if seen != len(pending) {
+		return errors.New("stage graph has a cycle")
+	}
+	return nil
+}
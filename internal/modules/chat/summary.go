@@ -0,0 +1,196 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"quavixAI/internal/db"
+	"quavixAI/internal/modules/llm"
+)
+
+// ================================
+// Rolling Session Summary
+// ================================
+
+const (
+	DefaultSummarizeAfterMessages = 40
+	DefaultSummarizeAfterTokens   = 3000
+	DefaultKeepRecent             = 10
+
+	summaryLockTTL = 2 * time.Minute
+)
+
+// SummaryConfig decides when a session is folded: once it holds more
+// than AfterMessages messages or AfterTokens tokens (whitespace words),
+// everything but the newest KeepRecent messages is merged into the
+// running summary.
+type SummaryConfig struct {
+	AfterMessages int
+	AfterTokens   int
+	KeepRecent    int
+}
+
+func (c *SummaryConfig) defaults() {
+	if c.KeepRecent <= 0 {
+		c.KeepRecent = DefaultKeepRecent
+	}
+	if c.AfterMessages <= c.KeepRecent {
+		c.AfterMessages = DefaultSummarizeAfterMessages
+		if c.AfterMessages <= c.KeepRecent {
+			c.AfterMessages = 2 * c.KeepRecent
+		}
+	}
+	if c.AfterTokens <= 0 {
+		c.AfterTokens = DefaultSummarizeAfterTokens
+	}
+}
+
+// SessionSummary is stored next to the session list. Folded counts every
+// message merged into Text so far.
+type SessionSummary struct {
+	Text      string    `json:"text"`
+	Folded    int       `json:"folded"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var errSessionChanged = errors.New("session changed while summarizing")
+
+func summaryKey(sessionID string) string {
+	return "session:" + sessionID + ":summary"
+}
+
+func (m *MemoryEngine) GetSummary(ctx context.Context, sessionID string) (*SessionSummary, error) {
+	data, err := m.redis.Get(ctx, summaryKey(sessionID))
+	if errors.Is(err, db.ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s SessionSummary
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, fmt.Errorf("corrupt session summary: %w", err)
+	}
+	return &s, nil
+}
+
+// SummarizeSession folds the oldest messages into the running summary
+// when the session is over budget (or always, with force). It returns
+// nil when nothing was folded, including when another run holds the
+// session's lock.
+func (m *MemoryEngine) SummarizeSession(ctx context.Context, sessionID string, force bool) (*SessionSummary, error) {
+	if m.redis == nil || m.llm == nil {
+		return nil, errors.New("session summarization not configured")
+	}
+
+	unlock, ok, err := m.redis.TryLock(ctx, summaryKey(sessionID)+":lock", summaryLockTTL)
+	if err != nil || !ok {
+		return nil, err
+	}
+	defer unlock()
+
+	key := sessionKey(sessionID)
+
+	items, _, err := m.redis.ListPage(ctx, key, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	fold := len(items) - m.summarize.KeepRecent
+	if fold <= 0 {
+		return nil, nil
+	}
+
+	msgs := make([]MemoryMessage, len(items))
+	tokens := 0
+	for i, item := range items {
+		if err := json.Unmarshal([]byte(item), &msgs[i]); err != nil {
+			return nil, fmt.Errorf("corrupt session message: %w", err)
+		}
+		tokens += len(strings.Fields(msgs[i].Content))
+	}
+
+	if !force && len(msgs) <= m.summarize.AfterMessages && tokens <= m.summarize.AfterTokens {
+		return nil, nil
+	}
+
+	prev, err := m.GetSummary(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil {
+		prev = &SessionSummary{}
+	}
+
+	resp, err := m.llm.Generate(ctx, llm.Request{
+		Mode:   llm.ModeAnalysis,
+		Prompt: m.prompt.BuildMemorySummaryPrompt(prev.Text, formatConversation(msgs[:fold])),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := m.prompt.ParseMemorySummary(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &SessionSummary{
+		Text:      text,
+		Folded:    prev.Folded + fold,
+		UpdatedAt: time.Now(),
+	}
+	b, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+
+	// drop exactly the folded messages and store the summary together;
+	// if the head moved (maxLen trim) the fold is discarded and retried
+	// on a later append
+	applied, err := m.redis.FoldListHead(ctx, key, int64(fold), items[fold-1], summaryKey(sessionID), string(b), m.sessionTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !applied {
+		return nil, errSessionChanged
+	}
+
+	return next, nil
+}
+
+// maybeSummarize starts a background summarization of sessionID unless
+// this process already has one in flight for it. The Redis lock taken
+// by SummarizeSession still guards against other instances.
+func (m *MemoryEngine) maybeSummarize(ctx context.Context, sessionID string) {
+	if _, busy := m.summarizing.LoadOrStore(sessionID, struct{}{}); busy {
+		return
+	}
+	go func() {
+		defer m.summarizing.Delete(sessionID)
+		m.summarizeInBackground(ctx, sessionID)
+	}()
+}
+
+func (m *MemoryEngine) summarizeInBackground(ctx context.Context, sessionID string) {
+	ctx, cancel := context.WithTimeout(ctx, summaryLockTTL)
+	defer cancel()
+
+	if _, err := m.SummarizeSession(ctx, sessionID, false); err != nil && !errors.Is(err, errSessionChanged) {
+		log.Printf("session %s: summarization failed: %v", sessionID, err)
+	}
+}
+
+func formatConversation(msgs []MemoryMessage) string {
+	var b strings.Builder
+	for _, msg := range msgs {
+		b.WriteString(msg.Role + ": " + msg.Content + "\n")
+	}
+	return b.String()
+}
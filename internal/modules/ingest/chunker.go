@@ -0,0 +1,82 @@
+package ingest
+
+import (
+	"regexp"
+)
+
+// ================================
+// Chunking
+// ================================
+
+const (
+	DefaultChunkTokens  = 256
+	DefaultChunkOverlap = 32
+)
+
+// ChunkConfig sizes windows in tokens. Tokens are approximated by
+// whitespace-separated words, which is close enough for budgeting.
+type ChunkConfig struct {
+	MaxTokens int
+	Overlap   int
+}
+
+func (c *ChunkConfig) defaults() {
+	if c.MaxTokens <= 0 {
+		c.MaxTokens = DefaultChunkTokens
+	}
+	if c.Overlap < 0 || c.Overlap >= c.MaxTokens {
+		c.Overlap = DefaultChunkOverlap
+		if c.Overlap >= c.MaxTokens {
+			c.Overlap = c.MaxTokens / 4
+		}
+	}
+}
+
+type Chunk struct {
+	Index   int
+	Heading string
+	Page    int
+	Text    string
+	Offset  int // byte offset in the parsed source text
+	Tokens  int
+}
+
+var wordRe = regexp.MustCompile(`\S+`)
+
+// ChunkSections never lets a chunk span two sections, so each chunk
+// belongs to exactly one heading. Long sections are cut into overlapping
+// windows of cfg.MaxTokens words.
+func ChunkSections(sections []Section, cfg ChunkConfig) []Chunk {
+	cfg.defaults()
+	step := cfg.MaxTokens - cfg.Overlap
+
+	var chunks []Chunk
+	for _, sec := range sections {
+		words := wordRe.FindAllStringIndex(sec.Text, -1)
+		if len(words) == 0 {
+			continue
+		}
+
+		for start := 0; start < len(words); start += step {
+			end := start + cfg.MaxTokens
+			if end > len(words) {
+				end = len(words)
+			}
+
+			from, to := words[start][0], words[end-1][1]
+			chunks = append(chunks, Chunk{
+				Index:   len(chunks),
+				Heading: sec.Heading,
+				Page:    sec.Page,
+				Text:    sec.Text[from:to],
+				Offset:  sec.Offset + from,
+				Tokens:  end - start,
+			})
+
+			if end == len(words) {
+				break
+			}
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkSections(t *testing.T) {
+	sections := []Section{
+		{Heading: "A", Text: "w0 w1 w2 w3 w4 w5 w6 w7 w8 w9", Offset: 100},
+		{Heading: "B", Page: 2, Text: "  x0 x1  ", Offset: 200},
+		{Heading: "C", Text: " \n "},
+	}
+
+	got := ChunkSections(sections, ChunkConfig{MaxTokens: 4, Overlap: 1})
+
+	want := []struct {
+		heading string
+		text    string
+		offset  int
+	}{
+		{"A", "w0 w1 w2 w3", 100},
+		{"A", "w3 w4 w5 w6", 109},
+		{"A", "w6 w7 w8 w9", 118},
+		{"B", "x0 x1", 202},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d chunks %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		c := got[i]
+		if c.Index != i || c.Heading != w.heading || c.Text != w.text || c.Offset != w.offset {
+			t.Errorf("chunk %d: got %d %q %q @%d, want %q %q @%d",
+				i, c.Index, c.Heading, c.Text, c.Offset, w.heading, w.text, w.offset)
+		}
+		if c.Tokens != len(strings.Fields(c.Text)) {
+			t.Errorf("chunk %d: %d tokens for %q", i, c.Tokens, c.Text)
+		}
+	}
+	if got[3].Page != 2 {
+		t.Errorf("chunk 3: page %d, want 2", got[3].Page)
+	}
+}
+
+func TestChunkConfigDefaults(t *testing.T) {
+	cases := []struct {
+		in, want ChunkConfig
+	}{
+		{ChunkConfig{}, ChunkConfig{MaxTokens: DefaultChunkTokens, Overlap: 0}},
+		{ChunkConfig{MaxTokens: 100, Overlap: 100}, ChunkConfig{MaxTokens: 100, Overlap: DefaultChunkOverlap}},
+		{ChunkConfig{MaxTokens: 8, Overlap: -1}, ChunkConfig{MaxTokens: 8, Overlap: 2}},
+	}
+	for _, tc := range cases {
+		got := tc.in
+		got.defaults()
+		if got != tc.want {
+			t.Errorf("%+v: got %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
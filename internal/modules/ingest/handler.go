@@ -0,0 +1,50 @@
+package ingest
+
+import (
+	"net/http"
+
+	"quavixAI/pkg/response"
+)
+
+// ================================
+// Handler
+// ================================
+
+type Handler struct {
+	pipeline *Pipeline
+}
+
+func NewHandler(p *Pipeline) *Handler {
+	return &Handler{pipeline: p}
+}
+
+// ================================
+// Ingest Endpoints
+// ================================
+
+func (h *Handler) Ingest(c response.Context) error {
+	var req Source
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+
+	res, err := h.pipeline.Ingest(c.Context(), req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(res))
+}
+
+func (h *Handler) Remove(c response.Context) error {
+	q := c.Request.URL.Query()
+
+	removed, err := h.pipeline.Remove(c.Context(), q.Get("namespace"), q.Get("source"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(map[string]interface{}{
+		"removed": removed,
+	}))
+}
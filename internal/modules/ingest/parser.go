@@ -0,0 +1,261 @@
+package ingest
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ================================
+// Formats
+// ================================
+
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatText     Format = "text"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf" // text already extracted from a PDF, pages separated by \f
+)
+
+// DetectFormat guesses the format from a file name when none was given.
+func DetectFormat(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".pdf":
+		return FormatPDF
+	default:
+		return FormatText
+	}
+}
+
+// ================================
+// Sections
+// ================================
+
+// Section is a contiguous run of text under one heading (or one PDF page).
+// Offset is the byte offset of Text within the parsed text.
+type Section struct {
+	Heading string
+	Page    int
+	Text    string
+	Offset  int
+}
+
+func Parse(format Format, content string) ([]Section, error) {
+	switch format {
+	case FormatMarkdown:
+		return parseMarkdown(content), nil
+	case FormatText, "":
+		return []Section{{Text: content}}, nil
+	case FormatHTML:
+		return parseHTML(content)
+	case FormatPDF:
+		return parsePDFText(content), nil
+	default:
+		return nil, errors.New("unsupported format: " + string(format))
+	}
+}
+
+// ================================
+// Markdown
+// ================================
+
+// mdHeading matches an ATX heading. As in CommonMark, a closing run of
+// "#" only counts when whitespace precedes it, so "## C#" keeps its "#".
+var mdHeading = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.+?)(?:[ \t]+#+)?[ \t]*$`)
+
+// parseMarkdown splits on ATX headings outside fenced code blocks. Each
+// section's heading is the full path, e.g. "Runbook > Rollback > DB".
+func parseMarkdown(content string) []Section {
+	var sections []Section
+	var path []string
+
+	start := 0
+	heading := ""
+	fence := "" // opening fence marker while inside a code block
+
+	flush := func(end int) {
+		text := content[start:end]
+		if strings.TrimSpace(text) != "" {
+			sections = append(sections, Section{Heading: heading, Text: text, Offset: start})
+		}
+	}
+
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		marker := fenceMarker(line)
+		switch {
+		case fence == "" && marker != "":
+			fence = marker
+		case fence != "":
+			// a fence only closes on the marker that opened it
+			if marker == fence {
+				fence = ""
+			}
+		default:
+			if m := mdHeading.FindStringSubmatch(strings.TrimRight(line, "\r\n")); m != nil {
+				flush(offset)
+
+				level := len(m[1])
+				if len(path) >= level {
+					path = path[:level-1]
+				}
+				for len(path) < level-1 {
+					path = append(path, "")
+				}
+				path = append(path, m[2])
+
+				heading = joinHeading(path)
+				start = offset + len(line)
+			}
+		}
+
+		offset += len(line)
+	}
+	flush(len(content))
+
+	return sections
+}
+
+func fenceMarker(line string) string {
+	trimmed := strings.TrimSpace(line)
+	for _, marker := range []string{"```", "~~~"} {
+		if strings.HasPrefix(trimmed, marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+func joinHeading(path []string) string {
+	parts := make([]string, 0, len(path))
+	for _, p := range path {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// ================================
+// HTML
+// ================================
+
+var htmlSkip = map[string]bool{"script": true, "style": true, "noscript": true, "head": true}
+
+var htmlBlock = map[string]bool{
+	"p": true, "div": true, "li": true, "tr": true, "br": true, "pre": true,
+	"section": true, "article": true, "blockquote": true, "table": true,
+}
+
+// parseHTML extracts visible text and starts a new section at every
+// h1-h6. Offsets refer to the extracted text, not the markup.
+func parseHTML(content string) ([]Section, error) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var sections []Section
+	var path []string
+	var buf strings.Builder
+	heading := ""
+	total := 0
+
+	flush := func() {
+		text := buf.String()
+		if strings.TrimSpace(text) != "" {
+			sections = append(sections, Section{Heading: heading, Text: text, Offset: total})
+		}
+		total += len(text)
+		buf.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if htmlSkip[n.Data] {
+				return
+			}
+			if level := headingLevel(n.Data); level > 0 {
+				flush()
+
+				if len(path) >= level {
+					path = path[:level-1]
+				}
+				for len(path) < level-1 {
+					path = append(path, "")
+				}
+				path = append(path, strings.Join(strings.Fields(nodeText(n)), " "))
+				heading = joinHeading(path)
+				return
+			}
+		}
+
+		if n.Type == html.TextNode {
+			buf.WriteString(n.Data)
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+
+		if n.Type == html.ElementNode && htmlBlock[n.Data] {
+			buf.WriteString("\n")
+		}
+	}
+	walk(doc)
+	flush()
+
+	return sections, nil
+}
+
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+// ================================
+// PDF Text
+// ================================
+
+// parsePDFText expects text already extracted from a PDF (pdftotext
+// output), where form feeds separate pages. Each page is one section.
+func parsePDFText(content string) []Section {
+	var sections []Section
+	offset := 0
+	for i, page := range strings.Split(content, "\f") {
+		if strings.TrimSpace(page) != "" {
+			sections = append(sections, Section{
+				Heading: "Page " + strconv.Itoa(i+1),
+				Page:    i + 1,
+				Text:    page,
+				Offset:  offset,
+			})
+		}
+		offset += len(page) + 1
+	}
+	return sections
+}
@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMdHeading(t *testing.T) {
+	cases := []struct {
+		line  string
+		level int
+		text  string
+	}{
+		{"# Runbook", 1, "Runbook"},
+		{"## C#", 2, "C#"},
+		{"### Rollback ###", 3, "Rollback"},
+		{"## F# and C# ##  ", 2, "F# and C#"},
+		{"   #### Indented", 4, "Indented"},
+		{"#hashtag", 0, ""},
+		{"    # code block", 0, ""},
+		{"####### too deep", 0, ""},
+	}
+	for _, tc := range cases {
+		m := mdHeading.FindStringSubmatch(tc.line)
+		if tc.level == 0 {
+			if m != nil {
+				t.Errorf("%q matched as a heading: %q", tc.line, m)
+			}
+			continue
+		}
+		if m == nil || len(m[1]) != tc.level || m[2] != tc.text {
+			t.Errorf("%q: got %q, want level %d %q", tc.line, m, tc.level, tc.text)
+		}
+	}
+}
+
+func TestParseMarkdown(t *testing.T) {
+	content := strings.Join([]string{
+		"intro",
+		"# Runbook",
+		"top",
+		"## Rollback",
+		"steps",
+		"```sh",
+		"# not a heading",
+		"~~~",
+		"## still code",
+		"```",
+		"### DB",
+		"restore",
+		"## C#",
+		"dotnet",
+		"# Other",
+		"end",
+	}, "\n")
+
+	want := []struct {
+		heading string
+		text    string
+	}{
+		{"", "intro\n"},
+		{"Runbook", "top\n"},
+		{"Runbook > Rollback", "steps\n```sh\n# not a heading\n~~~\n## still code\n```\n"},
+		{"Runbook > Rollback > DB", "restore\n"},
+		{"Runbook > C#", "dotnet\n"},
+		{"Other", "end"},
+	}
+
+	got := parseMarkdown(content)
+	if len(got) != len(want) {
+		t.Fatalf("got %d sections %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		s := got[i]
+		if s.Heading != w.heading || s.Text != w.text {
+			t.Errorf("section %d: got %q %q, want %q %q", i, s.Heading, s.Text, w.heading, w.text)
+		}
+		if content[s.Offset:s.Offset+len(s.Text)] != s.Text {
+			t.Errorf("section %d: offset %d does not point at its text", i, s.Offset)
+		}
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	content := `<html><head><title>skip</title></head><body>
+<h1>Runbook</h1><p>top</p>
+<script>alert("x")</script>
+<h2>Roll <em>back</em></h2><p>steps</p><ul><li>one</li><li>two</li></ul>
+<h3>DB</h3><p>restore</p>
+<h2>Verify</h2><p>check</p>
+</body></html>`
+
+	want := []struct {
+		heading string
+		text    string
+	}{
+		{"Runbook", "top"},
+		{"Runbook > Roll back", "steps one two"},
+		{"Runbook > Roll back > DB", "restore"},
+		{"Runbook > Verify", "check"},
+	}
+
+	got, err := parseHTML(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d sections %+v, want %d", len(got), got, len(want))
+	}
+	offset := 0
+	for i, w := range want {
+		s := got[i]
+		if text := strings.Join(strings.Fields(s.Text), " "); s.Heading != w.heading || text != w.text {
+			t.Errorf("section %d: got %q %q, want %q %q", i, s.Heading, text, w.heading, w.text)
+		}
+		if s.Offset < offset {
+			t.Errorf("section %d: offset %d goes back before %d", i, s.Offset, offset)
+		}
+		offset = s.Offset + len(s.Text)
+	}
+}
+
+func TestParsePDFText(t *testing.T) {
+	content := "first page\n\f\f  \fthird page\nmore\f"
+
+	got := parsePDFText(content)
+	if len(got) != 2 {
+		t.Fatalf("got %d sections %+v, want 2", len(got), got)
+	}
+
+	pages := []int{1, 4}
+	for i, s := range got {
+		if s.Page != pages[i] || s.Heading != "Page "+strconv.Itoa(pages[i]) {
+			t.Errorf("section %d: page %d %q, want page %d", i, s.Page, s.Heading, pages[i])
+		}
+		if content[s.Offset:s.Offset+len(s.Text)] != s.Text {
+			t.Errorf("section %d: offset %d does not point at its text", i, s.Offset)
+		}
+	}
+}
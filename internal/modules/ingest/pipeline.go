@@ -0,0 +1,185 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+)
+
+// ================================
+// Source / Result
+// ================================
+
+// Source is one file to ingest. Name identifies it across re-ingests
+// (path or URL); Format is detected from Name when empty.
+// Namespace is a tenant ID for that tenant's own documents; the default,
+// empty namespace is shared by everyone.
+type Source struct {
+	Name      string                 `json:"source"`
+	Format    Format                 `json:"format"`
+	Content   string                 `json:"content"`
+	Namespace string                 `json:"namespace"`
+	Meta      map[string]interface{} `json:"meta"`
+}
+
+type Result struct {
+	Source   string        `json:"source"`
+	Version  string        `json:"version"`
+	Format   Format        `json:"format"`
+	Sections int           `json:"sections"`
+	Chunks   int           `json:"chunks"`
+	Replaced int64         `json:"replaced"`
+	Took     time.Duration `json:"took"`
+}
+
+// DocumentType is the Meta["type"] of every ingested chunk.
+const DocumentType = "knowledge"
+
+// ================================
+// Pipeline
+// ================================
+
+type Config struct {
+	Store    vector.Store
+	Embedder vector.Embedder
+	Chunking ChunkConfig
+}
+
+type Pipeline struct {
+	store    vector.Store
+	embed    vector.Embedder
+	chunking ChunkConfig
+}
+
+func NewPipeline(cfg Config) *Pipeline {
+	cfg.Chunking.defaults()
+	return &Pipeline{
+		store:    cfg.Store,
+		embed:    cfg.Embedder,
+		chunking: cfg.Chunking,
+	}
+}
+
+// Ingest parses, chunks and embeds a source and replaces whatever an
+// earlier ingest of the same source stored. Chunk ids are derived from
+// the source name and a hash of its content, so re-ingesting unchanged
+// content rewrites the same rows, and a changed file first writes its
+// new chunks and then drops the previous version's.
+func (p *Pipeline) Ingest(ctx context.Context, src Source) (*Result, error) {
+	start := time.Now()
+
+	if src.Name == "" {
+		return nil, errors.New("missing source name")
+	}
+	if src.Format == "" {
+		src.Format = DetectFormat(src.Name)
+	}
+
+	sections, err := Parse(src.Format, src.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := ChunkSections(sections, p.chunking)
+	version := contentHash(src.Content)
+	sourceKey := contentHash(src.Namespace + "\x00" + src.Name)
+
+	docs := make([]vector.Document, 0, len(chunks))
+	for _, c := range chunks {
+		embedText := c.Text
+		if c.Heading != "" {
+			embedText = c.Heading + "\n\n" + c.Text
+		}
+
+		vec, err := p.embed.Embed(ctx, embedText)
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d: %w", c.Index, err)
+		}
+
+		meta := map[string]interface{}{}
+		for k, v := range src.Meta {
+			meta[k] = v
+		}
+		// knowledge is shared; a caller must not pass it off as someone's
+		delete(meta, types.MetaUserID)
+		delete(meta, types.MetaTenantID)
+		meta["type"] = DocumentType
+		meta["source"] = src.Name
+		meta["source_version"] = version
+		meta["format"] = string(src.Format)
+		meta["section"] = c.Heading
+		meta["offset"] = c.Offset
+		meta["chunk"] = c.Index
+		meta["tokens"] = c.Tokens
+		if c.Page > 0 {
+			meta["page"] = c.Page
+		}
+
+		docs = append(docs, vector.Document{
+			ID:        fmt.Sprintf("kb:%s:%s:%d", sourceKey[:16], version[:12], c.Index),
+			Namespace: src.Namespace,
+			Content:   c.Text,
+			Vector:    vec,
+			Meta:      meta,
+		})
+	}
+
+	if err := p.store.StoreBatch(ctx, docs); err != nil {
+		return nil, err
+	}
+
+	replaced, err := p.store.DeleteWhere(ctx, vector.Filter{
+		Namespace:      src.Namespace,
+		ExactNamespace: true,
+		Equals: map[string]interface{}{
+			"type":   DocumentType,
+			"source": src.Name,
+		},
+		NotEquals: map[string]interface{}{
+			"source_version": version,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		Source:   src.Name,
+		Version:  version,
+		Format:   src.Format,
+		Sections: len(sections),
+		Chunks:   len(docs),
+		Replaced: replaced,
+		Took:     time.Since(start),
+	}, nil
+}
+
+// Remove deletes every chunk of a source.
+func (p *Pipeline) Remove(ctx context.Context, namespace, name string) (int64, error) {
+	if name == "" {
+		return 0, errors.New("missing source name")
+	}
+	return p.store.DeleteWhere(ctx, vector.Filter{
+		Namespace:      namespace,
+		ExactNamespace: true,
+		Equals: map[string]interface{}{
+			"type":   DocumentType,
+			"source": name,
+		},
+	})
+}
+
+// ================================
+// Helpers
+// ================================
+
+func contentHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
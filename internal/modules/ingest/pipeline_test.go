@@ -0,0 +1,121 @@
+package ingest
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"testing"
+
+	"quavixAI/internal/modules/types"
+	"quavixAI/internal/modules/vector"
+)
+
+// hashEmbedder maps each text to a fixed pseudo-random vector.
+type hashEmbedder struct{}
+
+func (hashEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	seed := h.Sum32()
+
+	vec := make([]float32, 4)
+	for i := range vec {
+		seed = seed*1664525 + 1013904223
+		vec[i] = float32(seed%1000) / 1000
+	}
+	return vec, nil
+}
+
+func newTestPipeline(t *testing.T) (*Pipeline, *vector.HNSWStore) {
+	t.Helper()
+	store := vector.NewHNSWStore(vector.HNSWConfig{Dimension: 4, Seed: 1})
+	return NewPipeline(Config{
+		Store:    store,
+		Embedder: hashEmbedder{},
+		Chunking: ChunkConfig{MaxTokens: 4, Overlap: 1},
+	}), store
+}
+
+// sourceChunks lists the contents of every stored chunk of a source.
+func sourceChunks(t *testing.T, store vector.Store, namespace, name string) []string {
+	t.Helper()
+	docs, err := store.Search(context.Background(), []float32{0.5, 0.5, 0.5, 0.5}, vector.SearchOptions{
+		Limit: 100,
+		Filter: vector.Filter{
+			Namespace:      namespace,
+			ExactNamespace: true,
+			Equals:         map[string]interface{}{"type": DocumentType, "source": name},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []string
+	for _, d := range docs {
+		out = append(out, d.Content)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestIngestReplacesPreviousVersion(t *testing.T) {
+	ctx := context.Background()
+	p, store := newTestPipeline(t)
+
+	v1 := Source{Name: "runbook.md", Content: "# Deploy\none two three four five six\n# Rollback\nrevert"}
+	first, err := p.Ingest(ctx, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Chunks != 3 || first.Replaced != 0 {
+		t.Fatalf("first ingest: %d chunks, %d replaced; want 3, 0", first.Chunks, first.Replaced)
+	}
+
+	// the same source in another namespace is left alone
+	other := v1
+	other.Namespace = "team"
+	if _, err := p.Ingest(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	// unchanged content rewrites the same chunks
+	again, err := p.Ingest(ctx, v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Replaced != 0 || len(sourceChunks(t, store, "", "runbook.md")) != 3 {
+		t.Fatalf("re-ingesting unchanged content replaced %d chunks", again.Replaced)
+	}
+
+	v2 := Source{Name: "runbook.md", Content: "# Deploy\nship it", Meta: map[string]interface{}{types.MetaUserID: "u1"}}
+	second, err := p.Ingest(ctx, v2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Chunks != 1 || second.Replaced != 3 {
+		t.Fatalf("second ingest: %d chunks, %d replaced; want 1, 3", second.Chunks, second.Replaced)
+	}
+	if got := sourceChunks(t, store, "", "runbook.md"); len(got) != 1 || got[0] != "ship it" {
+		t.Fatalf("default namespace holds %q, want only the new version", got)
+	}
+	if got := sourceChunks(t, store, "team", "runbook.md"); len(got) != 3 {
+		t.Fatalf("team namespace holds %d chunks, want 3", len(got))
+	}
+
+	docs, _ := store.Search(ctx, []float32{0.5, 0.5, 0.5, 0.5}, vector.SearchOptions{
+		Limit:  1,
+		Filter: vector.Filter{Equals: map[string]interface{}{"source_version": second.Version}},
+	})
+	if len(docs) != 1 || docs[0].Meta[types.MetaUserID] != nil {
+		t.Fatalf("ingested chunk kept owner meta: %v", docs)
+	}
+
+	removed, err := p.Remove(ctx, "", "runbook.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || len(sourceChunks(t, store, "team", "runbook.md")) != 3 {
+		t.Fatalf("remove deleted %d chunks or touched another namespace", removed)
+	}
+}
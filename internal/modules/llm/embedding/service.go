@@ -1 +1,75 @@
 package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ================================
+// Embedder
+// ================================
+
+// Embedder is the raw text -> vector call (llm.Manager implements it).
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// ================================
+// Service
+// ================================
+
+// Service wraps an Embedder with the model identity and dimension the
+// vectors are expected to have, and checks every vector against them.
+type Service struct {
+	embedder  Embedder
+	model     string
+	dimension int
+}
+
+func NewService(e Embedder, model string, dimension int) *Service {
+	return &Service{
+		embedder:  e,
+		model:     model,
+		dimension: dimension,
+	}
+}
+
+func (s *Service) Model() string  { return s.model }
+func (s *Service) Dimension() int { return s.dimension }
+
+func (s *Service) Embed(ctx context.Context, text string) ([]float32, error) {
+	if s.embedder == nil {
+		return nil, errors.New("embedding provider not configured")
+	}
+	if text == "" {
+		return nil, errors.New("empty text")
+	}
+
+	vec, err := s.embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.dimension > 0 && len(vec) != s.dimension {
+		return nil, fmt.Errorf("embedding model %q returned %d dimensions, expected %d", s.model, len(vec), s.dimension)
+	}
+
+	return vec, nil
+}
+
+// EmbedBatch embeds texts in order and stops at the first failure.
+func (s *Service) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vec, err := s.Embed(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("text %d: %w", i, err)
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
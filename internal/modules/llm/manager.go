@@ -3,9 +3,11 @@ package llm
 import (
 	"context"
 	"errors"
+	"log"
 	"time"
 
 	"quavixAI/internal/db"
+	"quavixAI/internal/modules/types"
 	"quavixAI/internal/modules/vector"
 )
 
@@ -89,7 +91,9 @@ func (m *Manager) Generate(ctx context.Context, req Request) (Response, error) {
 	// ================================
 	// Memory Hooks
 	// ================================
-	_ = m.storeMemory(ctx, req, resp)
+	if err := m.storeMemory(ctx, req, resp); err != nil {
+		log.Printf("llm: storing response memory failed: %v", err)
+	}
 
 	return resp, nil
 }
@@ -98,29 +102,64 @@ func (m *Manager) Generate(ctx context.Context, req Request) (Response, error) {
 // Memory Layer
 // ================================
 
+// MemoryTypeResponse is the Meta["type"] of raw responses kept by storeMemory.
+const MemoryTypeResponse = "llm_response"
+
+func lastResponseKey(userID string) string {
+	return "llm:last_response:" + userID
+}
+
+// storeMemory keeps responses for the owner attached to ctx (see
+// types.WithOwner). Without one there is nobody to scope them to, so
+// nothing is stored.
 func (m *Manager) storeMemory(ctx context.Context, req Request, resp Response) error {
+	owner, ok := types.OwnerFrom(ctx)
+	if !ok {
+		return nil
+	}
+
 	// Redis short-term memory
 	if m.redis != nil {
-		_ = m.redis.Set(ctx, "llm:last_response", resp.Text, 30*time.Minute)
+		_ = m.redis.Set(ctx, lastResponseKey(owner.UserID), resp.Text, 30*time.Minute)
 	}
 
 	// Vector long-term memory
 	if m.vector != nil {
+		meta := owner.Meta()
+		meta["type"] = MemoryTypeResponse
+		meta["mode"] = string(req.Mode)
+		meta["provider"] = resp.Provider
+		meta["model"] = resp.Model
+
+		// stores reject documents without an embedding
+		emb, err := m.Embed(ctx, resp.Text)
+		if err != nil {
+			return err
+		}
+
 		doc := vector.Document{
 			ID:      generateID(),
 			Content: resp.Text,
-			Meta: map[string]string{
-				"mode":     string(req.Mode),
-				"provider": resp.Provider,
-				"model":    resp.Model,
-			},
+			Vector:  emb,
+			Meta:    meta,
+		}
+		if err := m.vector.Store(ctx, doc); err != nil {
+			return err
 		}
-		_ = m.vector.Store(ctx, doc)
 	}
 
 	return nil
 }
 
+// ForgetUser drops the short-term memory kept for userID. Long-term
+// documents are purged through the vector store by their owner tag.
+func (m *Manager) ForgetUser(ctx context.Context, userID string) (int64, error) {
+	if m.redis == nil {
+		return 0, nil
+	}
+	return m.redis.DeleteKeys(ctx, lastResponseKey(userID))
+}
+
 // ================================
 // Embeddings API
 // ================================
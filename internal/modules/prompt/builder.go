@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"text/template"
 
@@ -16,16 +17,38 @@ import (
 // ================================
 
 type Builder interface {
-	BuildFiveWhyPrompt(level int, question string) string
+	BuildFiveWhyPrompt(level int, question string, docs []types.Citation) string
 	BuildEvaluationPrompt(question, answer string) string
 	BuildNextWhyPrompt(answer string) string
 	BuildRootCausePrompt(steps []types.FiveWhyStep) string
 	BuildSolutionPrompt(rc types.RootCauseResult, steps []types.FiveWhyStep) string
 	BuildReframePrompt(original string, rc types.RootCauseResult) string
-
+	BuildRerankPrompt(query string, documents []string) string
+	BuildImportancePrompt(memoryType, content string) string
+	BuildMemorySummaryPrompt(summary, conversation string) string
+	BuildGraphExtractionPrompt(question string, steps []types.FiveWhyStep, rc types.RootCauseResult) string
+	BuildFishbonePrompt(problem, category string) string
+	BuildFishboneSynthesisPrompt(problem string, categories []types.FishboneCategory) string
+	BuildFaultTreePrompt(topEvent, event string, depth int) string
+	BuildA3SectionPrompt(problem, section string, prior []types.A3Section) string
+	BuildCritiquePrompt(steps []types.FiveWhyStep, rc types.RootCauseResult) string
+	BuildRevisionPrompt(steps []types.FiveWhyStep, rc types.RootCauseResult, critique types.Critique) string
+	BuildJudgePrompt(steps []types.FiveWhyStep, initial, proposed types.RootCauseResult, transcript []types.DebateTurn) string
+
+	ParseEvaluation(raw string, out *types.EvaluationVerdict) error
 	ParseRootCause(raw string, out *types.RootCauseResult) error
 	ParseSolution(raw string, out *types.SolutionResult) error
 	ParseReframe(raw string, out *types.ReframedQuestion) error
+	ParseRerankScores(raw string, n int) ([]float64, error)
+	ParseImportanceScore(raw string) (float64, error)
+	ParseMemorySummary(raw string) (string, error)
+	ParseWhyQuestion(raw string) (string, error)
+	ParseGraphExtraction(raw string, out *types.GraphExtraction) error
+	ParseFishboneCategory(raw string, out *types.FishboneCategory) error
+	ParseFaultTreeGate(raw string, out *types.FaultTreeGate) error
+	ParseA3Section(raw string) (string, error)
+	ParseCritique(raw string, out *types.Critique) error
+	ParseRuling(raw string, out *types.DebateRuling) error
 }
 
 // ================================
@@ -42,10 +65,13 @@ func NewBuilder() Builder {
 // Template Builders
 // ================================
 
-func (b *PromptBuilder) BuildFiveWhyPrompt(level int, question string) string {
+// BuildFiveWhyPrompt shows docs, when any were retrieved, under their
+// refs and asks for them to be cited.
+func (b *PromptBuilder) BuildFiveWhyPrompt(level int, question string, docs []types.Citation) string {
 	data := map[string]interface{}{
-		"Level":    level,
-		"Question": question,
+		"Level":     level,
+		"Question":  question,
+		"Documents": formatDocuments(docs),
 	}
 	return render(FiveWhyTemplate, data)
 }
@@ -66,16 +92,9 @@ func (b *PromptBuilder) BuildNextWhyPrompt(answer string) string {
 }
 
 func (b *PromptBuilder) BuildRootCausePrompt(steps []types.FiveWhyStep) string {
-	var chain strings.Builder
-	for _, s := range steps {
-		chain.WriteString(fmt.Sprintf(
-			"WHY %d:\nQ: %s\nA: %s\nANALYSIS: %s\n\n",
-			s.Level, s.Question, s.Answer, s.Analysis,
-		))
-	}
-
 	data := map[string]interface{}{
-		"Chain": chain.String(),
+		"Chain":     formatChain(steps),
+		"Documents": formatDocuments(types.StepCitations(steps)),
 	}
 
 	return render(RootCauseTemplate, data)
@@ -104,10 +123,188 @@ func (b *PromptBuilder) BuildReframePrompt(original string, rc types.RootCauseRe
 	return render(ReframeTemplate, data)
 }
 
+// BuildRerankPrompt asks for all documents to be rated in one call.
+func (b *PromptBuilder) BuildRerankPrompt(query string, documents []string) string {
+	data := map[string]interface{}{
+		"Query":     query,
+		"Documents": documents,
+	}
+
+	return render(RerankTemplate, data)
+}
+
+func (b *PromptBuilder) BuildImportancePrompt(memoryType, content string) string {
+	data := map[string]interface{}{
+		"Type":    memoryType,
+		"Content": content,
+	}
+
+	return render(ImportanceTemplate, data)
+}
+
+// BuildMemorySummaryPrompt folds conversation into an existing running
+// summary (empty for the first fold).
+func (b *PromptBuilder) BuildMemorySummaryPrompt(summary, conversation string) string {
+	data := map[string]interface{}{
+		"Summary":      summary,
+		"Conversation": conversation,
+	}
+
+	return render(MemorySummaryTemplate, data)
+}
+
+func (b *PromptBuilder) BuildGraphExtractionPrompt(question string, steps []types.FiveWhyStep, rc types.RootCauseResult) string {
+	var chain strings.Builder
+	for _, s := range steps {
+		chain.WriteString(fmt.Sprintf("WHY %d:\nQ: %s\nA: %s\n\n", s.Level, s.Question, s.Answer))
+	}
+
+	data := map[string]interface{}{
+		"Question":  question,
+		"Chain":     chain.String(),
+		"RootCause": rc.RootCause,
+	}
+
+	return render(GraphExtractionTemplate, data)
+}
+
+func (b *PromptBuilder) BuildFishbonePrompt(problem, category string) string {
+	data := map[string]interface{}{
+		"Problem":  problem,
+		"Category": category,
+	}
+	return render(FishboneCategoryTemplate, data)
+}
+
+func (b *PromptBuilder) BuildFishboneSynthesisPrompt(problem string, categories []types.FishboneCategory) string {
+	var diagram strings.Builder
+	for _, c := range categories {
+		diagram.WriteString(c.Category + ":\n")
+		for _, cause := range c.Causes {
+			diagram.WriteString(fmt.Sprintf("- %s (likelihood %.2f)\n", cause.Cause, cause.Likelihood))
+			for _, sub := range cause.SubCauses {
+				diagram.WriteString("  - " + sub + "\n")
+			}
+		}
+		diagram.WriteString("\n")
+	}
+
+	data := map[string]interface{}{
+		"Problem": problem,
+		"Diagram": diagram.String(),
+	}
+	return render(FishboneSynthesisTemplate, data)
+}
+
+func (b *PromptBuilder) BuildFaultTreePrompt(topEvent, event string, depth int) string {
+	data := map[string]interface{}{
+		"TopEvent": topEvent,
+		"Event":    event,
+		"Depth":    depth,
+	}
+	return render(FaultTreeTemplate, data)
+}
+
+func (b *PromptBuilder) BuildCritiquePrompt(steps []types.FiveWhyStep, rc types.RootCauseResult) string {
+	data := map[string]interface{}{
+		"Chain":      formatChain(steps),
+		"RootCause":  rc.RootCause,
+		"Confidence": fmt.Sprintf("%.2f", rc.Confidence),
+	}
+	return render(CritiqueTemplate, data)
+}
+
+func (b *PromptBuilder) BuildRevisionPrompt(steps []types.FiveWhyStep, rc types.RootCauseResult, critique types.Critique) string {
+	var c strings.Builder
+	c.WriteString(critique.Summary + "\n")
+	for _, o := range critique.Objections {
+		c.WriteString("- " + o + "\n")
+	}
+
+	data := map[string]interface{}{
+		"Chain":      formatChain(steps),
+		"RootCause":  rc.RootCause,
+		"Confidence": fmt.Sprintf("%.2f", rc.Confidence),
+		"Critique":   c.String(),
+	}
+	return render(RevisionTemplate, data)
+}
+
+func (b *PromptBuilder) BuildJudgePrompt(steps []types.FiveWhyStep, initial, proposed types.RootCauseResult, transcript []types.DebateTurn) string {
+	var t strings.Builder
+	for _, turn := range transcript {
+		t.WriteString(fmt.Sprintf("[round %d] %s: %s\n", turn.Round, strings.ToUpper(turn.Role), turn.Content))
+		for _, o := range turn.Objections {
+			t.WriteString("  - " + o + "\n")
+		}
+	}
+
+	data := map[string]interface{}{
+		"Chain":      formatChain(steps),
+		"Initial":    initial.RootCause,
+		"Proposed":   proposed.RootCause,
+		"Confidence": fmt.Sprintf("%.2f", proposed.Confidence),
+		"Transcript": t.String(),
+	}
+	return render(JudgeTemplate, data)
+}
+
+// BuildA3SectionPrompt asks for one of A3Sections given the sections
+// written before it.
+func (b *PromptBuilder) BuildA3SectionPrompt(problem, section string, prior []types.A3Section) string {
+	guidance := ""
+	for _, s := range A3Sections {
+		if s.Title == section {
+			guidance = s.Guidance
+		}
+	}
+
+	var report strings.Builder
+	for i, s := range prior {
+		if i > 0 {
+			report.WriteString("\n")
+		}
+		report.WriteString(fmt.Sprintf("%s:\n%s\n", strings.ToUpper(s.Title), s.Content))
+	}
+
+	data := map[string]interface{}{
+		"Problem":  problem,
+		"Section":  section,
+		"Guidance": guidance,
+		"Prior":    report.String(),
+	}
+	return render(A3SectionTemplate, data)
+}
+
 // ================================
 // Parsers
 // ================================
 
+// ParseEvaluation reads the evaluation verdict. Output that is not the
+// requested JSON is kept as a plain analysis with no verdict.
+func (b *PromptBuilder) ParseEvaluation(raw string, out *types.EvaluationVerdict) error {
+	jsonStr, err := extractJSON(raw)
+	if err == nil {
+		err = json.Unmarshal([]byte(jsonStr), out)
+	}
+	if err != nil {
+		*out = types.EvaluationVerdict{Analysis: strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(raw), "ANALYSIS:"))}
+		return nil
+	}
+
+	out.DepthScore = math.Max(0, math.Min(1, out.DepthScore))
+
+	branches := out.Branches[:0]
+	for _, br := range out.Branches {
+		if br = strings.TrimSpace(br); br != "" {
+			branches = append(branches, br)
+		}
+	}
+	out.Branches = branches
+
+	return nil
+}
+
 func (b *PromptBuilder) ParseRootCause(raw string, out *types.RootCauseResult) error {
 	jsonStr, err := extractJSON(raw)
 	if err != nil {
@@ -132,10 +329,240 @@ func (b *PromptBuilder) ParseReframe(raw string, out *types.ReframedQuestion) er
 	return json.Unmarshal([]byte(jsonStr), out)
 }
 
+// ParseRerankScores returns the 0-10 rating of each of the n documents,
+// in prompt order.
+func (b *PromptBuilder) ParseRerankScores(raw string, n int) ([]float64, error) {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Scores []float64 `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &out); err != nil {
+		return nil, err
+	}
+	if len(out.Scores) != n {
+		return nil, fmt.Errorf("got %d relevance scores for %d documents", len(out.Scores), n)
+	}
+	for _, s := range out.Scores {
+		if s < 0 || s > 10 {
+			return nil, fmt.Errorf("relevance score %v out of range", s)
+		}
+	}
+
+	return out.Scores, nil
+}
+
+// ParseImportanceScore returns the 1-10 importance rating.
+func (b *PromptBuilder) ParseImportanceScore(raw string) (float64, error) {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	var out struct {
+		Importance *float64 `json:"importance"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &out); err != nil {
+		return 0, err
+	}
+	if out.Importance == nil {
+		return 0, errors.New("missing importance score")
+	}
+	if *out.Importance < 1 || *out.Importance > 10 {
+		return 0, fmt.Errorf("importance score %v out of range", *out.Importance)
+	}
+
+	return *out.Importance, nil
+}
+
+func (b *PromptBuilder) ParseGraphExtraction(raw string, out *types.GraphExtraction) error {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(jsonStr), out)
+}
+
+// ParseFishboneCategory drops causes without text and clamps each
+// likelihood to [0,1].
+func (b *PromptBuilder) ParseFishboneCategory(raw string, out *types.FishboneCategory) error {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return err
+	}
+
+	causes := out.Causes[:0]
+	for _, c := range out.Causes {
+		c.Cause = strings.TrimSpace(c.Cause)
+		if c.Cause == "" {
+			continue
+		}
+		c.Likelihood = math.Max(0, math.Min(1, c.Likelihood))
+		causes = append(causes, c)
+	}
+	out.Causes = causes
+
+	return nil
+}
+
+// ParseFaultTreeGate normalizes the gate and treats a decomposition
+// without causes as a basic event.
+func (b *PromptBuilder) ParseFaultTreeGate(raw string, out *types.FaultTreeGate) error {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return err
+	}
+
+	causes := out.Causes[:0]
+	for _, c := range out.Causes {
+		if c = strings.TrimSpace(c); c != "" {
+			causes = append(causes, c)
+		}
+	}
+	out.Causes = causes
+
+	out.Gate = strings.ToUpper(strings.TrimSpace(out.Gate))
+	if out.Basic || len(out.Causes) == 0 {
+		out.Basic = true
+		out.Gate = ""
+		out.Causes = nil
+		return nil
+	}
+	if out.Gate != types.GateAND && out.Gate != types.GateOR {
+		return fmt.Errorf("unknown fault tree gate %q", out.Gate)
+	}
+
+	return nil
+}
+
+func (b *PromptBuilder) ParseCritique(raw string, out *types.Critique) error {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return err
+	}
+
+	objections := out.Objections[:0]
+	for _, o := range out.Objections {
+		if o = strings.TrimSpace(o); o != "" {
+			objections = append(objections, o)
+		}
+	}
+	out.Objections = objections
+
+	// a critic without objections has nothing to argue
+	if len(out.Objections) == 0 {
+		out.Concede = true
+	}
+	return nil
+}
+
+// ParseRuling requires a root cause and clamps the confidence to [0,1].
+func (b *PromptBuilder) ParseRuling(raw string, out *types.DebateRuling) error {
+	jsonStr, err := extractJSON(raw)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(jsonStr), out); err != nil {
+		return err
+	}
+
+	out.RootCause = strings.TrimSpace(out.RootCause)
+	if out.RootCause == "" {
+		return errors.New("ruling has no root cause")
+	}
+	out.Confidence = math.Max(0, math.Min(1, out.Confidence))
+	out.Debate = nil // never taken from the model
+
+	return nil
+}
+
+// ParseA3Section strips the "SECTION:" label the template asks for.
+func (b *PromptBuilder) ParseA3Section(raw string) (string, error) {
+	text := strings.TrimSpace(raw)
+	if i := strings.Index(text, "SECTION:"); i >= 0 {
+		text = strings.TrimSpace(text[i+len("SECTION:"):])
+	}
+	if text == "" {
+		return "", errors.New("empty a3 section")
+	}
+	return text, nil
+}
+
+// ParseMemorySummary strips the "MEMORY:" label the template asks for.
+func (b *PromptBuilder) ParseMemorySummary(raw string) (string, error) {
+	text := strings.TrimSpace(raw)
+	if i := strings.Index(text, "MEMORY:"); i >= 0 {
+		text = strings.TrimSpace(text[i+len("MEMORY:"):])
+	}
+	if text == "" {
+		return "", errors.New("empty memory summary")
+	}
+	return text, nil
+}
+
+// ParseWhyQuestion strips the "WHY QUESTION:" / "NEXT WHY:" labels the
+// 5-Why templates ask for.
+func (b *PromptBuilder) ParseWhyQuestion(raw string) (string, error) {
+	text := strings.TrimSpace(raw)
+	for _, label := range []string{"WHY QUESTION:", "NEXT WHY:"} {
+		if i := strings.Index(text, label); i >= 0 {
+			text = strings.TrimSpace(text[i+len(label):])
+			break
+		}
+	}
+	if text == "" {
+		return "", errors.New("empty why question")
+	}
+	return text, nil
+}
+
 // ================================
 // Utilities
 // ================================
 
+// formatChain renders 5-Why steps for the prompts that reason over the
+// whole chain.
+func formatChain(steps []types.FiveWhyStep) string {
+	var chain strings.Builder
+	for _, s := range steps {
+		label := fmt.Sprintf("WHY %d", s.Level)
+		if s.Branch != "" {
+			label += " (branch " + s.Branch + ")"
+		}
+		chain.WriteString(fmt.Sprintf(
+			"%s:\nQ: %s\nA: %s\nANALYSIS: %s\n\n",
+			label, s.Question, s.Answer, s.Analysis,
+		))
+	}
+	return chain.String()
+}
+
+// formatDocuments lists docs one per line under their refs; empty when
+// there are none, so templates can leave the section out.
+func formatDocuments(docs []types.Citation) string {
+	var out strings.Builder
+	for _, d := range docs {
+		source := ""
+		if d.Source != "" {
+			source = " (" + d.Source + ")"
+		}
+		out.WriteString(fmt.Sprintf("[%s]%s %s\n", d.Ref, source, d.Snippet))
+	}
+	return out.String()
+}
+
 func render(tpl string, data map[string]interface{}) string {
 	t, err := template.New("prompt").Parse(tpl)
 	if err != nil {
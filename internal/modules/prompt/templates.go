@@ -9,6 +9,11 @@ package prompt
 // - Question Reframing
 // - Planning
 // - Diagnosis
+// - Memory reranking
+// - Memory importance rating
+// - Entity and causal relation extraction
+// - Fishbone, fault tree and A3 analyses
+// - Root cause debate (critic, proposer, judge)
 
 // ================================
 // Core Prompt Templates
@@ -29,7 +34,13 @@ Rules:
 - No solutions
 - No explanations
 - No suggestions
-
+{{if .Documents}}
+Reference Documents:
+{{.Documents}}
+- Ground your output in the reference documents where they apply
+- Cite every document you rely on by its ID in brackets, e.g. [D1]
+- Cite only the IDs listed above
+{{end}}
 Output format:
 WHY QUESTION:`
 
@@ -49,13 +60,27 @@ Evaluate the answer for:
 - logical depth
 - systemic nature
 
+Then decide:
+- is_root_cause: true only if the answer names a systemic, structurally
+  actionable cause with nothing meaningful left to ask WHY about
+- depth_score: 0.0 (surface symptom) to 1.0 (fundamental systemic cause)
+- branches: other independent causes the answer implies that deserve
+  their own WHY (empty if the causal chain is linear)
+
+Output JSON schema:
+{
+  "analysis": "",
+  "is_root_cause": false,
+  "depth_score": 0.0,
+  "branches": [""]
+}
+
 Rules:
 - No new questions
 - No solutions
 - No rephrasing
 
-Output format:
-ANALYSIS:`
+Return ONLY valid JSON.`
 
 const NextWhyTemplate = `You are a causal reasoning engine.
 
@@ -82,7 +107,10 @@ const RootCauseTemplate = `You are a root-cause analysis AI system.
 
 5-Why Chain:
 {{.Chain}}
-
+{{if .Documents}}
+Documents Cited by the Chain:
+{{.Documents}}
+{{end}}
 Objective:
 Extract the TRUE ROOT CAUSE.
 
@@ -108,7 +136,106 @@ Rules:
 - Not a symptom
 - Not a surface cause
 - Not a human blame statement
-- Must be structurally actionable
+- Must be structurally actionable{{if .Documents}}
+- End each evidence entry with the IDs of the documents that support it, e.g. "... [D2]"
+- Cite only the IDs listed above{{end}}
+
+Return ONLY valid JSON.`
+
+// ================================
+// Root Cause Debate
+// ================================
+
+const CritiqueTemplate = `You are a critic reviewing a root-cause analysis. Your job is to find its weaknesses.
+
+5-Why Chain (the evidence):
+{{.Chain}}
+Proposed Root Cause:
+"{{.RootCause}}" (confidence {{.Confidence}})
+
+Objective:
+Challenge the proposed root cause against the evidence.
+
+Look for:
+- claims the chain does not support
+- symptoms or proximate causes presented as the root cause
+- branches of the chain the root cause ignores
+- blame on people instead of systems
+
+Output JSON schema:
+{
+  "summary": "",
+  "objections": [""],
+  "concede": false
+}
+
+Rules:
+- Every objection must cite the chain
+- Set concede to true (and leave objections empty) only if the root cause is fully supported
+- No solutions
+
+Return ONLY valid JSON.`
+
+const RevisionTemplate = `You are the analyst who proposed a root cause, answering a critic.
+
+5-Why Chain (the evidence):
+{{.Chain}}
+Your Root Cause:
+"{{.RootCause}}" (confidence {{.Confidence}})
+
+Critique:
+{{.Critique}}
+Objective:
+Revise the root cause where the objections hold; defend it where they do not.
+Adjust the confidence to match how well the evidence now supports it.
+
+Output JSON schema:
+{
+  "root_cause": "",
+  "confidence": 0.0,
+  "evidence": [""],
+  "category": "",
+  "impact_scope": "",
+  "rationale": ""
+}
+
+Rules:
+- rationale answers each objection: accepted or rebutted, and why
+- Root cause must be systemic and structurally actionable
+- Not a human blame statement
+
+Return ONLY valid JSON.`
+
+const JudgeTemplate = `You are an impartial judge settling a root-cause debate.
+
+5-Why Chain (the evidence):
+{{.Chain}}
+Initial Root Cause:
+"{{.Initial}}"
+
+Final Proposal:
+"{{.Proposed}}" (confidence {{.Confidence}})
+
+Debate Transcript:
+{{.Transcript}}
+Objective:
+Decide the root cause best supported by the evidence. You may keep the
+initial one, accept the final proposal, or merge them.
+
+Output JSON schema:
+{
+  "root_cause": "",
+  "confidence": 0.0,
+  "evidence": [""],
+  "category": "",
+  "impact_scope": "",
+  "rationale": ""
+}
+
+Rules:
+- Judge by the evidence, not by who argued last
+- Lower the confidence for objections that were never answered
+- rationale explains the ruling in at most three sentences
 
 Return ONLY valid JSON.`
 
@@ -177,6 +304,140 @@ Rules:
 
 Return ONLY valid JSON.`
 
+// ================================
+// Fishbone (Ishikawa) Analysis
+// ================================
+
+const FishboneCategoryTemplate = `You are a quality engineer running an Ishikawa (fishbone) analysis.
+
+Problem (the fish head):
+"{{.Problem}}"
+
+Category:
+{{.Category}}
+
+Objective:
+List the plausible causes of the problem that belong to this category only.
+
+Output JSON schema:
+{
+  "category": "{{.Category}}",
+  "causes": [
+    {"cause": "", "sub_causes": [""], "likelihood": 0.0}
+  ]
+}
+
+Rules:
+- At most 5 causes, most likely first
+- likelihood is 0.0 (implausible) to 1.0 (almost certainly contributing)
+- Causes must be specific to the problem, not generic checklists
+- No solutions
+
+Return ONLY valid JSON.`
+
+const FishboneSynthesisTemplate = `You are a root-cause analysis AI system.
+
+Problem:
+"{{.Problem}}"
+
+Fishbone Diagram:
+{{.Diagram}}
+Objective:
+Weigh the causes across every category and extract the TRUE ROOT CAUSE.
+
+Output JSON schema:
+{
+  "root_cause": "",
+  "confidence": 0.0,
+  "evidence": [""],
+  "category": "",
+  "impact_scope": ""
+}
+
+Rules:
+- category is the fishbone category the root cause belongs to
+- evidence lists the causes from the diagram that support it
+- Not a symptom
+- Not a human blame statement
+- Must be structurally actionable
+
+Return ONLY valid JSON.`
+
+// ================================
+// Fault Tree Analysis
+// ================================
+
+const FaultTreeTemplate = `You are a reliability engineer building a fault tree.
+
+Top Event:
+"{{.TopEvent}}"
+
+Event to decompose (depth {{.Depth}}):
+"{{.Event}}"
+
+Objective:
+Decompose the event into its immediate contributing events.
+
+Gates:
+- OR: any single cause is enough to trigger the event
+- AND: the causes must all occur together
+
+Output JSON schema:
+{
+  "gate": "OR",
+  "causes": [""],
+  "basic": false
+}
+
+Rules:
+- Set basic to true (and leave causes empty) when the event is a basic
+  failure: a component fault, human action or external condition that
+  needs no further decomposition
+- At most 4 causes, each a concrete event, not a category
+- Causes must be immediate, not root causes several levels down
+
+Return ONLY valid JSON.`
+
+// ================================
+// A3 Report
+// ================================
+
+// A3Sections are the sections of an A3 report in writing order, each
+// with the guidance given to the model.
+var A3Sections = []struct {
+	Title    string
+	Guidance string
+}{
+	{"Background", "Why this problem matters to the business and who is affected."},
+	{"Current Condition", "What is happening now, with facts and numbers; where and how often it occurs."},
+	{"Goal", "The measurable target condition and by when."},
+	{"Root Cause Analysis", "The root cause behind the gap between current condition and goal, and the evidence for it."},
+	{"Countermeasures", "Countermeasures that address the root cause, not the symptoms."},
+	{"Implementation Plan", "Who does what by when to put the countermeasures in place."},
+	{"Follow-up", "How results are checked against the goal and how the learning is standardised."},
+}
+
+const A3SectionTemplate = `You are a lean practitioner writing an A3 problem-solving report.
+
+Problem:
+"{{.Problem}}"
+{{if .Prior}}
+Report so far:
+{{.Prior}}{{end}}
+Objective:
+Write the "{{.Section}}" section of the A3 report.
+
+Section guidance:
+{{.Guidance}}
+
+Rules:
+- Build on the sections already written; do not repeat them
+- Be concrete and measurable where possible
+- At most 120 words
+
+Output format:
+SECTION:`
+
 // ================================
 // Planning
 // ================================
@@ -225,17 +486,118 @@ Rules:
 Output format:
 DIAGNOSIS:`
 
+// ================================
+// Memory Reranking
+// ================================
+
+const RerankTemplate = `You are a retrieval relevance judge.
+
+Query:
+"{{.Query}}"
+
+Candidate Documents:
+{{range $i, $d := .Documents}}[{{$i}}] "{{$d}}"
+{{end}}
+Objective:
+Rate how useful each document is for answering or investigating the query.
+
+Scale:
+- 0 = unrelated
+- 5 = same topic, indirectly useful
+- 10 = directly answers or explains the query
+
+Output JSON schema:
+{
+  "scores": [0]
+}
+
+Rules:
+- One score per document, in the order given ({{len .Documents}} scores)
+- Judge relevance only, not writing quality
+- Exact matches of identifiers, error codes or service names count strongly
+
+Return ONLY valid JSON.`
+
+// ================================
+// Memory Importance
+// ================================
+
+const ImportanceTemplate = `You are a long-term memory curator.
+
+Memory Type:
+{{.Type}}
+
+Memory:
+"{{.Content}}"
+
+Objective:
+Rate how important this memory is to keep for future investigations.
+
+Scale:
+- 1 = mundane small talk or transient output
+- 5 = useful context about the user's systems or problems
+- 10 = confirmed root cause, decision or lesson learned
+
+Output JSON schema:
+{
+  "importance": 1
+}
+
+Return ONLY valid JSON.`
+
+// ================================
+// Knowledge Graph Extraction
+// ================================
+
+const GraphExtractionTemplate = `You are a knowledge graph extraction AI.
+
+Problem:
+"{{.Question}}"
+
+5-Why Chain:
+{{.Chain}}
+Root Cause:
+"{{.RootCause}}"
+
+Objective:
+Extract the entities involved and the cause -> effect relations between them.
+
+Entity kinds:
+- system
+- component
+- team
+- process
+- resource
+- event
+
+Output JSON schema:
+{
+  "entities": [{"name": "", "kind": ""}],
+  "relations": [{"cause": "", "effect": "", "evidence": ""}]
+}
+
+Rules:
+- Use short canonical names ("deploy pipeline", not "the deployment pipeline we use")
+- Every relation's cause and effect must appear in entities
+- Only include relations the chain supports; evidence quotes or paraphrases it
+- No people's names
+
+Return ONLY valid JSON.`
+
 // ================================
 // Memory Summarization
 // ================================
 
 const MemorySummaryTemplate = `You are a memory compression AI.
-
+{{if .Summary}}
+Existing Summary:
+{{.Summary}}
+{{end}}
 Conversation Data:
 {{.Conversation}}
 
 Objective:
-Summarize into long-term semantic memory.
+Summarize into long-term semantic memory.{{if .Summary}} Fold the conversation into the existing summary; the result replaces it.{{end}}
 
 Rules:
 - Preserve meaning
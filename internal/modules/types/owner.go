@@ -0,0 +1,42 @@
+package types
+
+import "context"
+
+// ================================
+// Ownership
+// ================================
+
+// Metadata keys every memory write is tagged with.
+const (
+	MetaUserID   = "userID"
+	MetaTenantID = "tenantID"
+)
+
+// Owner identifies who a piece of memory belongs to. TenantID is empty
+// for users that are not part of a tenant.
+type Owner struct {
+	UserID   string `json:"user_id"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// Meta returns the ownership tags to merge into document metadata.
+func (o Owner) Meta() map[string]interface{} {
+	meta := map[string]interface{}{MetaUserID: o.UserID}
+	if o.TenantID != "" {
+		meta[MetaTenantID] = o.TenantID
+	}
+	return meta
+}
+
+type ownerKey struct{}
+
+// WithOwner attaches o to ctx so that writes made deeper in the call
+// chain (e.g. by the LLM manager) are tagged with it.
+func WithOwner(ctx context.Context, o Owner) context.Context {
+	return context.WithValue(ctx, ownerKey{}, o)
+}
+
+func OwnerFrom(ctx context.Context) (Owner, bool) {
+	o, ok := ctx.Value(ownerKey{}).(Owner)
+	return o, ok && o.UserID != ""
+}
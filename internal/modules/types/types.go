@@ -9,6 +9,61 @@ type FiveWhyStep struct {
 	Question string `json:"question"`
 	Answer   string `json:"answer"`
 	Analysis string `json:"analysis"`
+
+	// set by adaptive runs: Branch is the dotted path in the why-tree
+	// ("1", "1.2", ...) and the rest comes from the evaluation verdict
+	Branch      string  `json:"branch,omitempty"`
+	IsRootCause bool    `json:"is_root_cause,omitempty"`
+	DepthScore  float64 `json:"depth_score,omitempty"`
+
+	// Citations are the retrieved documents the answer cites;
+	// InvalidCitations are refs it cited that were never shown to it.
+	Citations        []Citation `json:"citations,omitempty"`
+	InvalidCitations []string   `json:"invalid_citations,omitempty"`
+}
+
+// Citation links a claim to a document retrieved for it. Ref is the
+// label the document was shown under in the prompt ("D1"); it is unique
+// within one run.
+type Citation struct {
+	Ref        string  `json:"ref"`
+	DocumentID string  `json:"document_id"`
+	Source     string  `json:"source,omitempty"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score,omitempty"`
+}
+
+// StepCitations lists the documents cited anywhere in steps, once each,
+// in order of first citation.
+func StepCitations(steps []FiveWhyStep) []Citation {
+	seen := make(map[string]bool)
+	var out []Citation
+	for _, s := range steps {
+		for _, c := range s.Citations {
+			if !seen[c.Ref] {
+				seen[c.Ref] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// EvaluationVerdict is the structured result of evaluating one WHY.
+// DepthScore is in [0,1]: how close the answer is to a systemic cause.
+// Branches lists independent contributing causes worth their own WHY.
+type EvaluationVerdict struct {
+	Analysis    string   `json:"analysis"`
+	IsRootCause bool     `json:"is_root_cause"`
+	DepthScore  float64  `json:"depth_score"`
+	Branches    []string `json:"branches"`
+}
+
+// WhyNode is one WHY in a why-tree; a node with several children forked
+// into parallel causal branches.
+type WhyNode struct {
+	Step     FiveWhyStep `json:"step"`
+	Children []*WhyNode  `json:"children,omitempty"`
 }
 
 // ================================
@@ -21,6 +76,68 @@ type RootCauseResult struct {
 	Evidence    []string `json:"evidence"`
 	Category    string   `json:"category"`
 	ImpactScope string   `json:"impact_scope"`
+
+	// Citations resolve the refs in Evidence against the documents the
+	// 5-Why steps cited.
+	Citations        []Citation `json:"citations,omitempty"`
+	InvalidCitations []string   `json:"invalid_citations,omitempty"`
+
+	// Debate explains how the result survived critique, when the
+	// debate stage ran.
+	Debate *RootCauseDebate `json:"debate,omitempty"`
+}
+
+// ================================
+// Root Cause Debate Models
+// ================================
+
+// Debate roles.
+const (
+	RoleCritic   = "critic"
+	RoleProposer = "proposer"
+	RoleJudge    = "judge"
+)
+
+// Critique is the critic's challenge of a proposed root cause. Concede
+// means nothing is left worth revising for.
+type Critique struct {
+	Summary    string   `json:"summary"`
+	Objections []string `json:"objections"`
+	Concede    bool     `json:"concede"`
+}
+
+// DebateRuling is a proposer's revision or the judge's final ruling.
+type DebateRuling struct {
+	RootCauseResult
+	Rationale string `json:"rationale"`
+}
+
+type DebateTurn struct {
+	Round      int      `json:"round"`
+	Role       string   `json:"role"`
+	Content    string   `json:"content"`
+	Objections []string `json:"objections,omitempty"`
+	RootCause  string   `json:"root_cause,omitempty"`
+	Confidence float64  `json:"confidence,omitempty"`
+}
+
+type ConfidenceChange struct {
+	Round  int     `json:"round"`
+	Role   string  `json:"role"`
+	From   float64 `json:"from"`
+	To     float64 `json:"to"`
+	Reason string  `json:"reason"`
+}
+
+// RootCauseDebate is the record of a critic/proposer/judge debate.
+// Outcome is "upheld" when the initial root cause survived unchanged
+// and "revised" otherwise.
+type RootCauseDebate struct {
+	Initial           string             `json:"initial_root_cause"`
+	Outcome           string             `json:"outcome"`
+	Rounds            int                `json:"rounds"`
+	Transcript        []DebateTurn       `json:"transcript"`
+	ConfidenceChanges []ConfidenceChange `json:"confidence_changes"`
 }
 
 // ================================
@@ -47,3 +164,76 @@ type ReframedQuestion struct {
 	Intent   string `json:"intent"`
 	Goal     string `json:"goal"`
 }
+
+// ================================
+// Fishbone (Ishikawa) Models
+// ================================
+
+type FishboneCause struct {
+	Cause      string   `json:"cause"`
+	SubCauses  []string `json:"sub_causes"`
+	Likelihood float64  `json:"likelihood"` // 0.0 - 1.0
+}
+
+// FishboneCategory is one bone of the diagram, e.g. "Machine" of the 6M.
+type FishboneCategory struct {
+	Category string          `json:"category"`
+	Causes   []FishboneCause `json:"causes"`
+}
+
+// ================================
+// Fault Tree Models
+// ================================
+
+// Fault tree gates. A node without a gate is a basic event.
+const (
+	GateAND = "AND"
+	GateOR  = "OR"
+)
+
+type FaultTreeNode struct {
+	Event    string           `json:"event"`
+	Gate     string           `json:"gate,omitempty"`
+	Children []*FaultTreeNode `json:"children,omitempty"`
+}
+
+// FaultTreeGate is one decomposition step: the causes of an event and
+// whether any one of them (OR) or all of them together (AND) trigger it.
+// Basic is true when the event needs no further decomposition.
+type FaultTreeGate struct {
+	Gate   string   `json:"gate"`
+	Causes []string `json:"causes"`
+	Basic  bool     `json:"basic"`
+}
+
+// ================================
+// A3 Report Models
+// ================================
+
+type A3Section struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+}
+
+// ================================
+// Knowledge Graph Models
+// ================================
+
+// Entity is a system, team, component, process or other named thing an
+// investigation talks about.
+type Entity struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// Relation states that Cause contributes to Effect (both entity names).
+type Relation struct {
+	Cause    string `json:"cause"`
+	Effect   string `json:"effect"`
+	Evidence string `json:"evidence"`
+}
+
+type GraphExtraction struct {
+	Entities  []Entity   `json:"entities"`
+	Relations []Relation `json:"relations"`
+}
@@ -54,7 +54,8 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, u)
 }
 
-// DeleteProfile deletes the authenticated user's profile.
+// DeleteProfile deletes the authenticated user's profile and all of
+// their data, and returns the deletion receipt.
 func (h *Handler) DeleteProfile(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -62,10 +63,11 @@ func (h *Handler) DeleteProfile(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.DeleteUserProfile(userID.(string)); err != nil {
+	receipt, err := h.svc.DeleteUserProfile(c.Request.Context(), userID.(string))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusNoContent, nil)
+	c.JSON(http.StatusOK, receipt)
 }
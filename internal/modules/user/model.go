@@ -1,6 +1,7 @@
 package user
 
 import (
+	"context"
 	"time"
 )
 
@@ -10,6 +11,7 @@ type User struct {
 	PasswordHash string    `json:"-" db:"password_hash"`
 	Name         string    `json:"name" db:"name"`
 	Role         string    `json:"role" db:"role"`
+	TenantID     string    `json:"tenant_id,omitempty" db:"tenant_id"`
 	APIKey       string    `json:"apiKey,omitempty" db:"api_key"` // ChatGPT API Key
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
@@ -19,3 +21,25 @@ type ProfileUpdateRequest struct {
 	Name   string `json:"name"`
 	APIKey string `json:"apiKey"`
 }
+
+// Purger deletes everything a module stores for a user. It must be
+// safe to run again after a partial failure.
+type Purger interface {
+	Purge(ctx context.Context, userID string) ([]PurgeResult, error)
+}
+
+// PurgeResult is how many records one store deleted.
+type PurgeResult struct {
+	Store   string `json:"store"`
+	Deleted int64  `json:"deleted"`
+}
+
+// DeletionReceipt is kept after the user row is gone, as proof of what
+// was erased and when.
+type DeletionReceipt struct {
+	ID          string        `json:"id" db:"id"`
+	UserID      string        `json:"user_id" db:"user_id"`
+	Results     []PurgeResult `json:"results" db:"-"`
+	RequestedAt time.Time     `json:"requested_at" db:"requested_at"`
+	CompletedAt time.Time     `json:"completed_at" db:"completed_at"`
+}
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"encoding/json"
+
 	"github.com/jmoiron/sqlx"
 )
 
@@ -29,3 +31,14 @@ func (r *Repository) DeleteUser(id string) error {
 	_, err := r.db.Exec(query, id)
 	return err
 }
+
+func (r *Repository) SaveReceipt(rc *DeletionReceipt) error {
+	results, err := json.Marshal(rc.Results)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO deletion_receipts (id, user_id, results, requested_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err = r.db.Exec(query, rc.ID, rc.UserID, results, rc.RequestedAt, rc.CompletedAt)
+	return err
+}
@@ -1,16 +1,22 @@
 package user
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Service struct {
-	repo *Repository
+	repo    *Repository
+	purgers []Purger
 }
 
-func NewService(r *Repository) *Service {
-	return &Service{repo: r}
+// NewService takes the modules whose data is erased with a profile.
+func NewService(r *Repository, purgers ...Purger) *Service {
+	return &Service{repo: r, purgers: purgers}
 }
 
 func (s *Service) GetUserProfile(userID string) (*User, error) {
@@ -38,10 +44,31 @@ func (s *Service) UpdateUserProfile(userID string, req *ProfileUpdateRequest) (*
 	return u, nil
 }
 
-func (s *Service) DeleteUserProfile(userID string) error {
-	err := s.repo.DeleteUser(userID)
-	if err != nil {
-		return errors.New("failed to delete user")
+// DeleteUserProfile purges the user's data from every module, then the
+// user row, and returns a receipt. If any purge fails the user is kept
+// so the request can be retried.
+func (s *Service) DeleteUserProfile(ctx context.Context, userID string) (*DeletionReceipt, error) {
+	receipt := &DeletionReceipt{
+		ID:          uuid.NewString(),
+		UserID:      userID,
+		RequestedAt: time.Now(),
+	}
+
+	for _, p := range s.purgers {
+		results, err := p.Purge(ctx, userID)
+		receipt.Results = append(receipt.Results, results...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge user data: %w", err)
+		}
+	}
+
+	if err := s.repo.DeleteUser(userID); err != nil {
+		return nil, errors.New("failed to delete user")
+	}
+	receipt.CompletedAt = time.Now()
+
+	if err := s.repo.SaveReceipt(receipt); err != nil {
+		return nil, fmt.Errorf("user deleted but receipt not saved: %w", err)
 	}
-	return nil
+	return receipt, nil
 }
@@ -0,0 +1,370 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ================================
+// Collection Model
+// ================================
+
+// DefaultCollection is backed by the historical vector_memory table.
+const DefaultCollection = "memory"
+
+type IndexType string
+
+const (
+	IndexIVFFlat IndexType = "ivfflat"
+	IndexHNSW    IndexType = "hnsw"
+	IndexNone    IndexType = "none"
+)
+
+// IndexParams holds build-time (Lists, M, EfConstruction) and query-time
+// (Probes, EfSearch) knobs; only those of the chosen index type apply.
+type IndexParams struct {
+	Lists          int `json:"lists,omitempty"`
+	Probes         int `json:"probes,omitempty"`
+	M              int `json:"m,omitempty"`
+	EfConstruction int `json:"ef_construction,omitempty"`
+	EfSearch       int `json:"ef_search,omitempty"`
+}
+
+type Collection struct {
+	Name      string      `json:"name"`
+	Dimension int         `json:"dimension"`
+	Metric    Metric      `json:"metric"`
+	Model     string      `json:"model"` // embedding model the vectors come from
+	Index     IndexType   `json:"index"`
+	Params    IndexParams `json:"params"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type CollectionInfo struct {
+	Collection
+	Documents int64 `json:"documents"`
+	SizeBytes int64 `json:"size_bytes"`
+}
+
+var collectionName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,47}$`)
+
+func (c *Collection) defaults() {
+	if c.Metric == "" {
+		c.Metric = MetricL2
+	}
+	if c.Index == "" {
+		c.Index = IndexIVFFlat
+	}
+	switch c.Index {
+	case IndexIVFFlat:
+		if c.Params.Lists <= 0 {
+			c.Params.Lists = 100
+		}
+	case IndexHNSW:
+		if c.Params.M <= 0 {
+			c.Params.M = 16
+		}
+		if c.Params.EfConstruction <= 0 {
+			c.Params.EfConstruction = 64
+		}
+	}
+}
+
+func (c Collection) Validate() error {
+	if !collectionName.MatchString(c.Name) {
+		return errors.New("collection name must match " + collectionName.String())
+	}
+	// pgvector indexes support up to 2000 dimensions
+	if c.Dimension <= 0 || c.Dimension > 2000 {
+		return fmt.Errorf("invalid dimension %d", c.Dimension)
+	}
+	if _, err := distanceOperator(c.Metric); err != nil {
+		return err
+	}
+	switch c.Index {
+	case IndexIVFFlat, IndexHNSW, IndexNone:
+	default:
+		return errors.New("unsupported index type: " + string(c.Index))
+	}
+	return nil
+}
+
+// Table is the Postgres table behind the collection. Names are
+// validated against collectionName, so they are safe to interpolate.
+func (c Collection) Table() string {
+	return "vector_" + c.Name
+}
+
+// ================================
+// Collection Manager
+// ================================
+
+type CollectionManager struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	stores map[string]*PgVectorStore
+}
+
+func NewCollectionManager(db *sql.DB) *CollectionManager {
+	return &CollectionManager{
+		db:     db,
+		stores: make(map[string]*PgVectorStore),
+	}
+}
+
+// Init creates the registry and registers the default collection. A
+// default collection registered before models were recorded adopts
+// defaultModel.
+func (m *CollectionManager) Init(ctx context.Context, defaultDimension int, defaultModel string) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS vector_collections (
+			name TEXT PRIMARY KEY,
+			dimension INT NOT NULL,
+			metric TEXT NOT NULL,
+			embedding_model TEXT NOT NULL DEFAULT '',
+			index_type TEXT NOT NULL,
+			params JSONB NOT NULL DEFAULT '{}',
+			created_at TIMESTAMPTZ DEFAULT NOW()
+		);`,
+
+		`ALTER TABLE vector_collections ADD COLUMN IF NOT EXISTS embedding_model TEXT NOT NULL DEFAULT '';`,
+	}
+	for _, q := range queries {
+		if _, err := m.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+
+	_, err := m.Get(ctx, DefaultCollection)
+	if err == nil {
+		_, err = m.db.ExecContext(ctx, `UPDATE vector_collections SET embedding_model = $2
+			WHERE name = $1 AND embedding_model = '';`, DefaultCollection, defaultModel)
+		return err
+	}
+	if !errors.Is(err, ErrCollectionNotFound) {
+		return err
+	}
+
+	_, err = m.Create(ctx, Collection{
+		Name:      DefaultCollection,
+		Dimension: defaultDimension,
+		Model:     defaultModel,
+	})
+	return err
+}
+
+var (
+	ErrCollectionNotFound = errors.New("collection not found")
+	ErrCollectionExists   = errors.New("collection already exists")
+)
+
+// Create registers a collection and creates its table and indexes.
+func (m *CollectionManager) Create(ctx context.Context, c Collection) (*Collection, error) {
+	c.defaults()
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	params, err := json.Marshal(c.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.db.ExecContext(ctx, `INSERT INTO vector_collections
+		(name, dimension, metric, embedding_model, index_type, params)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (name) DO NOTHING;`,
+		c.Name, c.Dimension, string(c.Metric), c.Model, string(c.Index), params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrCollectionExists
+	}
+
+	store := NewPgVectorCollection(m.db, c)
+	if err := store.Init(ctx); err != nil {
+		// leave no registry entry for a collection without a table
+		_, _ = m.db.ExecContext(ctx, `DELETE FROM vector_collections WHERE name = $1;`, c.Name)
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.stores[c.Name] = store
+	m.mu.Unlock()
+
+	return m.Get(ctx, c.Name)
+}
+
+func (m *CollectionManager) Get(ctx context.Context, name string) (*Collection, error) {
+	row := m.db.QueryRowContext(ctx, `SELECT name, dimension, metric, embedding_model, index_type, params, created_at
+		FROM vector_collections WHERE name = $1;`, name)
+
+	c, err := scanCollection(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCollectionNotFound
+	}
+	return c, err
+}
+
+func (m *CollectionManager) List(ctx context.Context) ([]Collection, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT name, dimension, metric, embedding_model, index_type, params, created_at
+		FROM vector_collections ORDER BY name;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Collection
+	for rows.Next() {
+		c, err := scanCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+// Inspect returns the collection with its row count and on-disk size.
+func (m *CollectionManager) Inspect(ctx context.Context, name string) (*CollectionInfo, error) {
+	c, err := m.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &CollectionInfo{Collection: *c}
+
+	query := fmt.Sprintf(`SELECT COUNT(*), pg_total_relation_size('%s') FROM %s;`, c.Table(), c.Table())
+	if err := m.db.QueryRowContext(ctx, query).Scan(&info.Documents, &info.SizeBytes); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// Drop deletes the collection's table and registry entry. The default
+// collection cannot be dropped.
+func (m *CollectionManager) Drop(ctx context.Context, name string) error {
+	if name == DefaultCollection {
+		return errors.New("the default collection cannot be dropped")
+	}
+
+	c, err := m.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, c.Table())); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vector_collections WHERE name = $1;`, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	m.forget(name)
+	return nil
+}
+
+// forget drops the cached store so the next Store call reloads the
+// collection from the registry.
+func (m *CollectionManager) forget(name string) {
+	m.mu.Lock()
+	delete(m.stores, name)
+	m.mu.Unlock()
+}
+
+// reload refreshes the cached store of a collection from the registry
+// in place, so holders of it write with the collection's current model.
+func (m *CollectionManager) reload(ctx context.Context, name string) error {
+	c, err := m.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	store, ok := m.stores[name]
+	m.mu.Unlock()
+	if ok {
+		store.adopt(*c)
+	}
+	return nil
+}
+
+// Attach makes store the one Store returns for its collection, so a
+// store created elsewhere (the default one) follows re-embedding swaps.
+func (m *CollectionManager) Attach(name string, store *PgVectorStore) {
+	m.mu.Lock()
+	m.stores[name] = store
+	m.mu.Unlock()
+}
+
+// Store returns the vector.Store for a registered collection.
+func (m *CollectionManager) Store(ctx context.Context, name string) (*PgVectorStore, error) {
+	m.mu.Lock()
+	store, ok := m.stores[name]
+	m.mu.Unlock()
+	if ok {
+		return store, nil
+	}
+
+	c, err := m.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	store = NewPgVectorCollection(m.db, *c)
+
+	m.mu.Lock()
+	m.stores[name] = store
+	m.mu.Unlock()
+
+	return store, nil
+}
+
+// ================================
+// Helpers
+// ================================
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCollection(row rowScanner) (*Collection, error) {
+	var c Collection
+	var metric, index string
+	var params []byte
+	var createdAt sql.NullTime
+
+	if err := row.Scan(&c.Name, &c.Dimension, &metric, &c.Model, &index, &params, &createdAt); err != nil {
+		return nil, err
+	}
+
+	c.Metric = Metric(metric)
+	c.Index = IndexType(index)
+	c.CreatedAt = createdAt.Time
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &c.Params); err != nil {
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
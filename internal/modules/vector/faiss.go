@@ -1 +1,998 @@
 package vector
+
+import (
+	"container/heap"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Pure-Go HNSW (Hierarchical Navigable Small World) index.
+// Embedded alternative to pgvector for small deployments: no CGO,
+// no FAISS, no database. Deletes are tombstones; tombstoned nodes
+// keep routing the graph but are never returned from Search, until
+// enough pile up that the graph is rebuilt without them.
+
+func init() {
+	// metadata values are stored as interface{}; gob needs the composite
+	// JSON shapes registered up front
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+}
+
+// ================================
+// Config
+// ================================
+
+type HNSWConfig struct {
+	Dimension      int
+	M              int // max links per node on upper layers (layer 0 uses 2*M)
+	EfConstruction int // candidate list size while inserting
+	EfSearch       int // candidate list size while querying
+	Metric         Metric
+	Model          string // embedding model stamped on documents that carry none
+	Path           string
+	SaveInterval   time.Duration // 0 saves only on Close
+	RebuildRatio   float64       // share of tombstoned nodes that triggers a rebuild
+	Seed           int64
+}
+
+func (c *HNSWConfig) defaults() {
+	if c.M <= 0 {
+		c.M = 16
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	if c.Metric == "" {
+		c.Metric = MetricL2
+	}
+	if c.RebuildRatio <= 0 {
+		c.RebuildRatio = 0.5
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+}
+
+// ================================
+// HNSW Store
+// ================================
+
+type hnswNode struct {
+	ID        string
+	Namespace string
+	Content   string
+	Vector    []float32
+	Meta      map[string]interface{}
+	Model     string
+	Level     int
+	CreatedAt time.Time
+	Links     [][]int
+	Deleted   bool
+
+	// term frequencies for BM25; rebuilt on load, not persisted
+	terms  map[string]int
+	length int
+}
+
+type HNSWStore struct {
+	mu sync.RWMutex
+
+	cfg  HNSWConfig
+	mult float64
+	rng  *rand.Rand
+	dist func(a, b []float32) float32
+
+	nodes    []*hnswNode
+	ids      map[string]int
+	entry    int
+	maxLevel int
+	deleted  int
+
+	dirty  bool       // changed since the last Save
+	saving sync.Mutex // orders Saves so an older snapshot never lands last
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// rebuilds below this many tombstones are not worth the pause
+const minRebuildTombstones = 64
+
+func NewHNSWStore(cfg HNSWConfig) *HNSWStore {
+	cfg.defaults()
+	return &HNSWStore{
+		cfg:   cfg,
+		mult:  1 / math.Log(float64(cfg.M)),
+		rng:   rand.New(rand.NewSource(cfg.Seed)),
+		dist:  distanceFunc(cfg.Metric),
+		ids:   make(map[string]int),
+		entry: -1,
+		stop:  make(chan struct{}),
+	}
+}
+
+// ================================
+// Init
+// ================================
+
+// Init loads the index saved at cfg.Path, if any, and starts saving it
+// every SaveInterval.
+func (h *HNSWStore) Init(ctx context.Context) error {
+	if h.cfg.Path == "" {
+		return nil
+	}
+	if _, err := os.Stat(h.cfg.Path); err == nil {
+		if err := h.Load(); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if h.cfg.SaveInterval > 0 {
+		go h.autosave()
+	}
+	return nil
+}
+
+func (h *HNSWStore) autosave() {
+	ticker := time.NewTicker(h.cfg.SaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.mu.RLock()
+			dirty := h.dirty
+			h.mu.RUnlock()
+			if !dirty {
+				continue
+			}
+			if err := h.Save(); err != nil {
+				log.Printf("hnsw: autosave failed: %v", err)
+			}
+		}
+	}
+}
+
+// ================================
+// Store Document
+// ================================
+
+func (h *HNSWStore) Store(ctx context.Context, doc Document) error {
+	if doc.ID == "" {
+		return errors.New("missing document id")
+	}
+	if len(doc.Vector) == 0 {
+		return errors.New("missing embedding vector")
+	}
+	if h.cfg.Dimension > 0 && len(doc.Vector) != h.cfg.Dimension {
+		return fmt.Errorf("vector dimension %d does not match index dimension %d", len(doc.Vector), h.cfg.Dimension)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.upsert(doc, time.Now())
+	h.maybeRebuild()
+	return nil
+}
+
+// StoreBatch validates every document first, then inserts them all in a
+// single locked pass.
+func (h *HNSWStore) StoreBatch(ctx context.Context, docs []Document) error {
+	for i, doc := range docs {
+		if doc.ID == "" {
+			return fmt.Errorf("document %d: missing document id", i)
+		}
+		if len(doc.Vector) == 0 {
+			return fmt.Errorf("document %d (%s): missing embedding vector", i, doc.ID)
+		}
+		if h.cfg.Dimension > 0 && len(doc.Vector) != h.cfg.Dimension {
+			return fmt.Errorf("document %d (%s): vector dimension %d does not match index dimension %d", i, doc.ID, len(doc.Vector), h.cfg.Dimension)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h.upsert(doc, now)
+	}
+	h.maybeRebuild()
+	return nil
+}
+
+// upsert tombstones any previous version and inserts the new one; callers hold the write lock.
+func (h *HNSWStore) upsert(doc Document, now time.Time) {
+	createdAt := now
+	if !doc.CreatedAt.IsZero() {
+		createdAt = doc.CreatedAt
+	}
+	if old, ok := h.ids[doc.ID]; ok {
+		createdAt = h.nodes[old].CreatedAt
+		h.tombstone(old)
+	}
+
+	model := doc.Model
+	if model == "" {
+		model = h.cfg.Model
+	}
+
+	h.insert(&hnswNode{
+		ID:        doc.ID,
+		Namespace: doc.Namespace,
+		Content:   doc.Content,
+		Vector:    append([]float32(nil), doc.Vector...),
+		Meta:      copyMeta(doc.Meta),
+		Model:     model,
+		CreatedAt: createdAt,
+	})
+}
+
+// ================================
+// Similarity Search
+// ================================
+
+func (h *HNSWStore) Search(ctx context.Context, vector []float32, opts SearchOptions) ([]Document, error) {
+	if len(vector) == 0 {
+		return nil, errors.New("empty query vector")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.entry < 0 {
+		return nil, nil
+	}
+
+	// the graph is only navigable under the metric it was built with
+	if opts.Metric != "" && opts.Metric != h.cfg.Metric {
+		return h.scan(vector, limit, opts), nil
+	}
+
+	ef := h.cfg.EfSearch
+	if ef < limit {
+		ef = limit
+	}
+
+	for {
+		found := h.search(vector, ef)
+
+		var results []Document
+		skipped := 0
+		for _, c := range found {
+			if opts.MinScore != 0 && Score(h.cfg.Metric, float64(c.dist)) < opts.MinScore {
+				// candidates are sorted, nothing further can qualify
+				return results, nil
+			}
+
+			n := h.nodes[c.id]
+			if n.Deleted || !opts.Filter.Match(n.document()) {
+				skipped++
+				continue
+			}
+			results = append(results, n.result(h.cfg.Metric, c.dist, opts.IncludeVectors))
+			if len(results) == limit {
+				return results, nil
+			}
+		}
+
+		// tombstones and filters can crowd live matches out of the
+		// candidate list; widen the beam until we have enough or have
+		// seen everything
+		if skipped == 0 || ef >= len(h.nodes) {
+			return results, nil
+		}
+		ef *= 2
+	}
+}
+
+// scan is an exact brute-force search, used for metrics the graph was not built on.
+func (h *HNSWStore) scan(vector []float32, limit int, opts SearchOptions) []Document {
+	dist := distanceFunc(opts.Metric)
+
+	var cands []candidate
+	for i, n := range h.nodes {
+		if n.Deleted || !opts.Filter.Match(n.document()) {
+			continue
+		}
+		d := dist(vector, n.Vector)
+		if opts.MinScore != 0 && Score(opts.Metric, float64(d)) < opts.MinScore {
+			continue
+		}
+		cands = append(cands, candidate{id: i, dist: d})
+	}
+	sortCandidates(cands)
+
+	if len(cands) > limit {
+		cands = cands[:limit]
+	}
+
+	results := make([]Document, len(cands))
+	for i, c := range cands {
+		results[i] = h.nodes[c.id].result(opts.Metric, c.dist, opts.IncludeVectors)
+	}
+	return results
+}
+
+func (n *hnswNode) document() Document {
+	return Document{
+		ID:        n.ID,
+		Namespace: n.Namespace,
+		Content:   n.Content,
+		Meta:      n.Meta,
+		CreatedAt: n.CreatedAt,
+		Model:     n.Model,
+		Dimension: len(n.Vector),
+	}
+}
+
+func (n *hnswNode) result(metric Metric, dist float32, withVector bool) Document {
+	doc := n.document()
+	doc.Meta = copyMeta(n.Meta)
+	doc.Distance = float64(dist)
+	doc.Score = Score(metric, doc.Distance)
+	if withVector {
+		doc.Vector = append([]float32(nil), n.Vector...)
+	}
+	return doc
+}
+
+// ================================
+// Lexical Search (BM25)
+// ================================
+
+// TextSearch ranks live documents matching opts.Filter with Okapi BM25.
+// Collection statistics are computed over the filtered set at query time,
+// which is fine at the sizes this backend targets.
+func (h *HNSWStore) TextSearch(ctx context.Context, query string, opts SearchOptions) ([]Document, error) {
+	qterms := Tokenize(query)
+	if len(qterms) == 0 {
+		return nil, errors.New("empty text query")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var docs []*hnswNode
+	for _, n := range h.nodes {
+		if !n.Deleted && opts.Filter.Match(n.document()) {
+			docs = append(docs, n)
+		}
+	}
+
+	index := make([]bm25Doc, len(docs))
+	for i, n := range docs {
+		index[i] = bm25Doc{terms: n.terms, length: n.length}
+	}
+
+	type hit struct {
+		node  *hnswNode
+		score float64
+	}
+	var hits []hit
+	for i, score := range bm25(qterms, index) {
+		if score > 0 {
+			hits = append(hits, hit{node: docs[i], score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].score > hits[j].score })
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	results := make([]Document, len(hits))
+	for i, hit := range hits {
+		doc := hit.node.document()
+		doc.Score = hit.score
+		if opts.IncludeVectors {
+			doc.Vector = append([]float32(nil), hit.node.Vector...)
+		}
+		results[i] = doc
+	}
+	return results, nil
+}
+
+// ================================
+// Metadata Update
+// ================================
+
+// UpdateMeta merges patch into the metadata of every listed live
+// document. The map is copied so callers' maps are never mutated.
+func (h *HNSWStore) UpdateMeta(ctx context.Context, ids []string, patch map[string]interface{}) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range ids {
+		idx, ok := h.ids[id]
+		if !ok {
+			continue
+		}
+		n := h.nodes[idx]
+
+		meta := make(map[string]interface{}, len(n.Meta)+len(patch))
+		for k, v := range n.Meta {
+			meta[k] = v
+		}
+		for k, v := range patch {
+			meta[k] = v
+		}
+		n.Meta = meta
+		h.dirty = true
+	}
+	return nil
+}
+
+// ================================
+// Delete
+// ================================
+
+func (h *HNSWStore) Delete(ctx context.Context, id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if idx, ok := h.ids[id]; ok {
+		h.tombstone(idx)
+		h.maybeRebuild()
+	}
+	return nil
+}
+
+// DeleteWhere tombstones every live document matching f.
+func (h *HNSWStore) DeleteWhere(ctx context.Context, f Filter) (int64, error) {
+	if f.IsEmpty() {
+		return 0, errors.New("refusing to delete with an empty filter")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var n int64
+	for _, idx := range h.ids {
+		if f.Match(h.nodes[idx].document()) {
+			h.tombstone(idx)
+			n++
+		}
+	}
+	h.maybeRebuild()
+	return n, nil
+}
+
+// ================================
+// Retention
+// ================================
+
+func (h *HNSWStore) Compact(ctx context.Context, policy RetentionPolicy, now time.Time) (EvictionStats, error) {
+	stats := EvictionStats{Type: policy.Type}
+	if err := policy.Validate(); err != nil {
+		return stats, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var live []int
+	for _, idx := range h.ids {
+		if t, ok := h.nodes[idx].Meta[metaType].(string); ok && t == policy.Type {
+			live = append(live, idx)
+		}
+	}
+
+	if policy.MaxAge > 0 {
+		cutoff := now.Add(-policy.MaxAge)
+		kept := live[:0]
+		for _, idx := range live {
+			if h.nodes[idx].CreatedAt.Before(cutoff) {
+				h.tombstone(idx)
+				stats.Expired++
+				continue
+			}
+			kept = append(kept, idx)
+		}
+		live = kept
+	}
+
+	if policy.MaxPerUser > 0 {
+		byUser := map[string][]int{}
+		for _, idx := range live {
+			user, _ := h.nodes[idx].Meta[metaUser].(string)
+			byUser[user] = append(byUser[user], idx)
+		}
+
+		live = live[:0]
+		for _, group := range byUser {
+			sort.Slice(group, func(i, j int) bool {
+				a, b := h.nodes[group[i]], h.nodes[group[j]]
+				if !a.CreatedAt.Equal(b.CreatedAt) {
+					return a.CreatedAt.After(b.CreatedAt)
+				}
+				return a.ID > b.ID
+			})
+			for i, idx := range group {
+				if i >= policy.MaxPerUser {
+					h.tombstone(idx)
+					stats.OverLimit++
+					continue
+				}
+				live = append(live, idx)
+			}
+		}
+	}
+
+	if policy.MinImportance > 0 {
+		for _, idx := range live {
+			if imp, ok := MetaNumber(h.nodes[idx].Meta[metaImportance]); ok && imp < policy.MinImportance {
+				h.tombstone(idx)
+				stats.LowImportance++
+			}
+		}
+	}
+
+	h.maybeRebuild()
+	return stats, nil
+}
+
+func (h *HNSWStore) tombstone(idx int) {
+	n := h.nodes[idx]
+	if n.Deleted {
+		return
+	}
+	n.Deleted = true
+	h.deleted++
+	h.dirty = true
+	delete(h.ids, n.ID)
+
+	// a tombstone only routes searches; drop the payload so deleted
+	// content is gone from memory and from the next snapshot
+	n.Content = ""
+	n.Meta = nil
+	n.terms, n.length = nil, 0
+}
+
+// maybeRebuild rebuilds the graph from the live nodes once tombstones
+// make up RebuildRatio of it. Tombstones keep routing searches, so until
+// then they cost memory and search time but not recall. Callers hold
+// the write lock.
+func (h *HNSWStore) maybeRebuild() {
+	if h.deleted < minRebuildTombstones || float64(h.deleted) < h.cfg.RebuildRatio*float64(len(h.nodes)) {
+		return
+	}
+
+	live := make([]*hnswNode, 0, len(h.nodes)-h.deleted)
+	for _, n := range h.nodes {
+		if !n.Deleted {
+			live = append(live, n)
+		}
+	}
+
+	h.nodes = make([]*hnswNode, 0, len(live))
+	h.ids = make(map[string]int, len(live))
+	h.entry = -1
+	h.maxLevel = 0
+	h.deleted = 0
+
+	for _, n := range live {
+		n.Links = nil
+		h.insert(n)
+	}
+}
+
+// ================================
+// Graph Construction
+// ================================
+
+func (h *HNSWStore) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.mult))
+}
+
+func (h *HNSWStore) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+func (h *HNSWStore) insert(n *hnswNode) {
+	n.terms, n.length = termFrequencies(n.Content)
+	n.Level = h.randomLevel()
+	n.Links = make([][]int, n.Level+1)
+
+	idx := len(h.nodes)
+	h.nodes = append(h.nodes, n)
+	h.ids[n.ID] = idx
+	h.dirty = true
+
+	if h.entry < 0 {
+		h.entry = idx
+		h.maxLevel = n.Level
+		return
+	}
+
+	ep := []candidate{{id: h.entry, dist: h.dist(n.Vector, h.nodes[h.entry].Vector)}}
+
+	// greedy descent through layers above the new node
+	for lc := h.maxLevel; lc > n.Level; lc-- {
+		ep = h.searchLayer(n.Vector, ep, 1, lc)
+	}
+
+	for lc := min(n.Level, h.maxLevel); lc >= 0; lc-- {
+		found := h.searchLayer(n.Vector, ep, h.cfg.EfConstruction, lc)
+
+		neighbors := found
+		if len(neighbors) > h.cfg.M {
+			neighbors = neighbors[:h.cfg.M]
+		}
+
+		for _, nb := range neighbors {
+			n.Links[lc] = append(n.Links[lc], nb.id)
+			h.link(nb.id, idx, lc)
+		}
+
+		ep = found
+	}
+
+	if n.Level > h.maxLevel {
+		h.entry = idx
+		h.maxLevel = n.Level
+	}
+}
+
+// link adds a back-reference and prunes the neighbor list to its closest maxLinks.
+func (h *HNSWStore) link(from, to, level int) {
+	node := h.nodes[from]
+	node.Links[level] = append(node.Links[level], to)
+
+	limit := h.maxLinks(level)
+	if len(node.Links[level]) <= limit {
+		return
+	}
+
+	cands := make([]candidate, len(node.Links[level]))
+	for i, id := range node.Links[level] {
+		cands[i] = candidate{id: id, dist: h.dist(node.Vector, h.nodes[id].Vector)}
+	}
+	sortCandidates(cands)
+
+	pruned := make([]int, limit)
+	for i := 0; i < limit; i++ {
+		pruned[i] = cands[i].id
+	}
+	node.Links[level] = pruned
+}
+
+// ================================
+// Graph Search
+// ================================
+
+func (h *HNSWStore) search(q []float32, ef int) []candidate {
+	ep := []candidate{{id: h.entry, dist: h.dist(q, h.nodes[h.entry].Vector)}}
+	for lc := h.maxLevel; lc > 0; lc-- {
+		ep = h.searchLayer(q, ep, 1, lc)
+	}
+	return h.searchLayer(q, ep, ef, 0)
+}
+
+// searchLayer returns up to ef nearest nodes on one layer, closest first.
+func (h *HNSWStore) searchLayer(q []float32, entry []candidate, ef, level int) []candidate {
+	visited := make(map[int]struct{}, ef*4)
+
+	cands := &minHeap{}
+	results := &maxHeap{}
+
+	for _, e := range entry {
+		visited[e.id] = struct{}{}
+		heap.Push(cands, e)
+		heap.Push(results, e)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for cands.Len() > 0 {
+		c := heap.Pop(cands).(candidate)
+		if results.Len() >= ef && c.dist > (*results)[0].dist {
+			break
+		}
+
+		node := h.nodes[c.id]
+		if level >= len(node.Links) {
+			continue
+		}
+
+		for _, nb := range node.Links[level] {
+			if _, seen := visited[nb]; seen {
+				continue
+			}
+			visited[nb] = struct{}{}
+
+			d := h.dist(q, h.nodes[nb].Vector)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(cands, candidate{id: nb, dist: d})
+				heap.Push(results, candidate{id: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]candidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(candidate)
+	}
+	return out
+}
+
+// ================================
+// Persistence
+// ================================
+
+type hnswSnapshot struct {
+	M              int
+	EfConstruction int
+	Dimension      int
+	Metric         Metric
+	Nodes          []*hnswNode
+	Entry          int
+	MaxLevel       int
+}
+
+// Save writes the index to cfg.Path atomically (temp file + rename),
+// creating its directory if needed.
+func (h *HNSWStore) Save() error {
+	if h.cfg.Path == "" {
+		return errors.New("hnsw index path not configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(h.cfg.Path), 0o755); err != nil {
+		return err
+	}
+
+	h.saving.Lock()
+	defer h.saving.Unlock()
+
+	// encoding and IO run outside the lock; writes made meanwhile mark
+	// the index dirty again for the next Save
+	h.mu.Lock()
+	snap := h.snapshot()
+	h.dirty = false
+	h.mu.Unlock()
+
+	if err := h.write(&snap); err != nil {
+		h.mu.Lock()
+		h.dirty = true
+		h.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// snapshot copies what Save writes; callers hold the lock. Nodes are
+// copied along with their link lists, which insert keeps appending to.
+// Vectors and metadata maps are replaced, never mutated, so they are
+// shared.
+func (h *HNSWStore) snapshot() hnswSnapshot {
+	nodes := make([]*hnswNode, len(h.nodes))
+	for i, n := range h.nodes {
+		c := *n
+		c.terms = nil
+		c.Links = make([][]int, len(n.Links))
+		for lc, links := range n.Links {
+			c.Links[lc] = append([]int(nil), links...)
+		}
+		nodes[i] = &c
+	}
+
+	return hnswSnapshot{
+		M:              h.cfg.M,
+		EfConstruction: h.cfg.EfConstruction,
+		Dimension:      h.cfg.Dimension,
+		Metric:         h.cfg.Metric,
+		Nodes:          nodes,
+		Entry:          h.entry,
+		MaxLevel:       h.maxLevel,
+	}
+}
+
+func (h *HNSWStore) write(snap *hnswSnapshot) error {
+	tmp, err := os.CreateTemp(filepath.Dir(h.cfg.Path), filepath.Base(h.cfg.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("hnsw encode failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.cfg.Path)
+}
+
+// Load replaces the in-memory index with the one stored at cfg.Path.
+func (h *HNSWStore) Load() error {
+	f, err := os.Open(h.cfg.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var snap hnswSnapshot
+	if err := gob.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("hnsw decode failed: %w", err)
+	}
+
+	if h.cfg.Dimension > 0 && snap.Dimension > 0 && snap.Dimension != h.cfg.Dimension {
+		return fmt.Errorf("hnsw index dimension %d does not match configured %d", snap.Dimension, h.cfg.Dimension)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the graph was built with these; keep them so new links stay consistent
+	h.cfg.M = snap.M
+	h.cfg.EfConstruction = snap.EfConstruction
+	h.mult = 1 / math.Log(float64(snap.M))
+	if snap.Metric != "" {
+		h.cfg.Metric = snap.Metric
+		h.dist = distanceFunc(snap.Metric)
+	}
+
+	h.nodes = snap.Nodes
+	h.entry = snap.Entry
+	h.maxLevel = snap.MaxLevel
+	h.ids = make(map[string]int, len(snap.Nodes))
+	h.deleted = 0
+	h.dirty = false
+
+	for i, n := range h.nodes {
+		if n.Deleted {
+			h.deleted++
+			continue
+		}
+		h.ids[n.ID] = i
+		n.terms, n.length = termFrequencies(n.Content)
+	}
+
+	return nil
+}
+
+// Close stops autosaving and flushes the index to disk when a path is
+// configured.
+func (h *HNSWStore) Close() error {
+	h.once.Do(func() { close(h.stop) })
+	if h.cfg.Path == "" {
+		return nil
+	}
+	return h.Save()
+}
+
+// ================================
+// Helpers
+// ================================
+
+type candidate struct {
+	id   int
+	dist float32
+}
+
+type minHeap []candidate
+
+func (m minHeap) Len() int            { return len(m) }
+func (m minHeap) Less(i, j int) bool  { return m[i].dist < m[j].dist }
+func (m minHeap) Swap(i, j int)       { m[i], m[j] = m[j], m[i] }
+func (m *minHeap) Push(x interface{}) { *m = append(*m, x.(candidate)) }
+func (m *minHeap) Pop() interface{} {
+	old := *m
+	c := old[len(old)-1]
+	*m = old[:len(old)-1]
+	return c
+}
+
+type maxHeap []candidate
+
+func (m maxHeap) Len() int            { return len(m) }
+func (m maxHeap) Less(i, j int) bool  { return m[i].dist > m[j].dist }
+func (m maxHeap) Swap(i, j int)       { m[i], m[j] = m[j], m[i] }
+func (m *maxHeap) Push(x interface{}) { *m = append(*m, x.(candidate)) }
+func (m *maxHeap) Pop() interface{} {
+	old := *m
+	c := old[len(old)-1]
+	*m = old[:len(old)-1]
+	return c
+}
+
+func sortCandidates(c []candidate) {
+	sort.Slice(c, func(i, j int) bool { return c[i].dist < c[j].dist })
+}
+
+func distanceFunc(metric Metric) func(a, b []float32) float32 {
+	switch metric {
+	case MetricCosine:
+		return cosineDistance
+	case MetricInnerProduct:
+		return negativeDot
+	default:
+		return l2
+	}
+}
+
+func l2(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return float32(math.Sqrt(float64(sum)))
+}
+
+func cosineDistance(a, b []float32) float32 {
+	var dot, na, nb float32
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/float32(math.Sqrt(float64(na)*float64(nb)))
+}
+
+func negativeDot(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += a[i] * b[i]
+	}
+	return -dot
+}
+
+func copyMeta(meta map[string]interface{}) map[string]interface{} {
+	if meta == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,267 @@
+package vector
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func randomDocs(rng *rand.Rand, n, dim int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		vec := make([]float32, dim)
+		for j := range vec {
+			vec[j] = rng.Float32()*2 - 1
+		}
+		docs[i] = Document{ID: fmt.Sprintf("doc-%d", i), Content: "doc", Vector: vec}
+	}
+	return docs
+}
+
+// exactNearest is a brute-force search over the live nodes.
+func exactNearest(h *HNSWStore, q []float32, k int) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var cands []candidate
+	for i, n := range h.nodes {
+		if !n.Deleted {
+			cands = append(cands, candidate{id: i, dist: l2(q, n.Vector)})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+	if len(cands) > k {
+		cands = cands[:k]
+	}
+
+	ids := make([]string, len(cands))
+	for i, c := range cands {
+		ids[i] = h.nodes[c.id].ID
+	}
+	return ids
+}
+
+// recallAtK is the share of the exact k nearest neighbours of each query
+// that the graph search returns.
+func recallAtK(t testing.TB, h *HNSWStore, queries [][]float32, k int) float64 {
+	ctx := context.Background()
+	hits, total := 0, 0
+	for _, q := range queries {
+		got, err := h.Search(ctx, q, SearchOptions{Limit: k})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := make(map[string]bool, k)
+		exact := exactNearest(h, q, k)
+		for _, id := range exact {
+			want[id] = true
+		}
+		for _, d := range got {
+			if want[d.ID] {
+				hits++
+			}
+		}
+		total += len(exact)
+	}
+	return float64(hits) / float64(total)
+}
+
+func storeAll(t testing.TB, h *HNSWStore, docs []Document) {
+	for _, d := range docs {
+		if err := h.Store(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newTestIndex(t testing.TB, n, dim int) (*HNSWStore, *rand.Rand) {
+	rng := rand.New(rand.NewSource(1))
+	h := NewHNSWStore(HNSWConfig{Dimension: dim, Seed: 1})
+	storeAll(t, h, randomDocs(rng, n, dim))
+	return h, rng
+}
+
+func queries(rng *rand.Rand, n, dim int) [][]float32 {
+	var qs [][]float32
+	for _, d := range randomDocs(rng, n, dim) {
+		qs = append(qs, d.Vector)
+	}
+	return qs
+}
+
+func TestHNSWRecall(t *testing.T) {
+	h, rng := newTestIndex(t, 2000, 32)
+	if r := recallAtK(t, h, queries(rng, 50, 32), 10); r < 0.9 {
+		t.Fatalf("recall@10 = %.3f, want >= 0.9", r)
+	}
+}
+
+func TestHNSWRebuildsAfterUpserts(t *testing.T) {
+	h, rng := newTestIndex(t, 500, 16)
+
+	// rewriting every document tombstones all of the originals
+	for round := 0; round < 3; round++ {
+		storeAll(t, h, randomDocs(rng, 500, 16))
+	}
+
+	h.mu.RLock()
+	nodes, deleted, live := len(h.nodes), h.deleted, len(h.ids)
+	h.mu.RUnlock()
+
+	if live != 500 {
+		t.Fatalf("live documents = %d, want 500", live)
+	}
+	if float64(deleted) >= h.cfg.RebuildRatio*float64(nodes) {
+		t.Fatalf("%d of %d nodes are tombstones, want a rebuild", deleted, nodes)
+	}
+	if r := recallAtK(t, h, queries(rng, 20, 16), 10); r < 0.9 {
+		t.Fatalf("recall@10 after rebuild = %.3f, want >= 0.9", r)
+	}
+}
+
+func TestHNSWSaveCreatesDirectory(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nested", "vector.hnsw")
+
+	h := NewHNSWStore(HNSWConfig{Dimension: 8, Path: path, Seed: 1})
+	docs := randomDocs(rand.New(rand.NewSource(1)), 50, 8)
+	storeAll(t, h, docs)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewHNSWStore(HNSWConfig{Dimension: 8, Path: path})
+	if err := loaded.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	got, err := loaded.Search(ctx, docs[7].Vector, SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != docs[7].ID {
+		t.Fatalf("got %v, want %s", got, docs[7].ID)
+	}
+}
+
+func BenchmarkHNSWSearch(b *testing.B) {
+	h, rng := newTestIndex(b, 5000, 64)
+	qs := queries(rng, 100, 64)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Search(ctx, qs[i%len(qs)], SearchOptions{Limit: 10}); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(recallAtK(b, h, qs, 10), "recall@10")
+}
+
+func TestHNSWStoreCopiesMeta(t *testing.T) {
+	ctx := context.Background()
+	h := NewHNSWStore(HNSWConfig{Dimension: 2, Seed: 1})
+
+	meta := map[string]interface{}{"type": "question"}
+	if err := h.Store(ctx, Document{ID: "a", Vector: []float32{1, 0}, Meta: meta}); err != nil {
+		t.Fatal(err)
+	}
+	meta["type"] = "changed"
+
+	got, err := h.Search(ctx, []float32{1, 0}, SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0].Meta["type"] != "question" {
+		t.Fatalf("stored meta followed the caller's map: %v", got[0].Meta)
+	}
+
+	got[0].Meta["type"] = "changed"
+	again, _ := h.Search(ctx, []float32{1, 0}, SearchOptions{Limit: 1})
+	if again[0].Meta["type"] != "question" {
+		t.Fatalf("stored meta followed a result's map: %v", again[0].Meta)
+	}
+}
+
+func TestHNSWSaveDuringWrites(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vector.hnsw")
+	h := NewHNSWStore(HNSWConfig{Dimension: 8, Path: path, Seed: 1})
+	docs := randomDocs(rand.New(rand.NewSource(1)), 400, 8)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, d := range docs {
+			if err := h.Store(ctx, d); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		if err := h.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewHNSWStore(HNSWConfig{Dimension: 8, Path: path})
+	if err := loaded.Init(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer loaded.Close()
+
+	if n := len(loaded.ids); n != len(docs) {
+		t.Fatalf("loaded %d documents, want %d", n, len(docs))
+	}
+}
+
+func TestHNSWSaveDropsDeletedContent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vector.hnsw")
+
+	h := NewHNSWStore(HNSWConfig{Dimension: 8, Path: path, Seed: 1})
+	docs := randomDocs(rand.New(rand.NewSource(1)), 20, 8)
+	docs[3].Content = "secret-runbook-content"
+	docs[3].Meta = map[string]interface{}{"userID": "secret-user"}
+	storeAll(t, h, docs)
+
+	n, err := h.DeleteWhere(ctx, Filter{Equals: map[string]interface{}{"userID": "secret-user"}})
+	if err != nil || n != 1 {
+		t.Fatalf("deleted %d, %v; want 1", n, err)
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"secret-runbook-content", "secret-user"} {
+		if bytes.Contains(raw, []byte(s)) {
+			t.Fatalf("saved index still contains %q", s)
+		}
+	}
+
+	got, err := h.Search(ctx, docs[3].Vector, SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID == docs[3].ID {
+		t.Fatalf("search returned %v after delete", got)
+	}
+}
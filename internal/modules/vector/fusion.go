@@ -0,0 +1,133 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// ================================
+// Reciprocal Rank Fusion
+// ================================
+
+const DefaultRRFK = 60
+
+// RankedList is one retriever's output, best first, with its fusion weight.
+type RankedList struct {
+	Docs   []Document
+	Weight float64
+}
+
+// FuseRRF merges rankings with weighted reciprocal rank fusion:
+// score(d) = sum over lists of weight / (k + rank(d)), rank starting at 1.
+// The first occurrence of a document supplies its fields (so put the
+// semantic list first to keep Distance); Score is replaced by the fused
+// score and results are ordered by it. The fused score is divided by its
+// maximum, so 1 means ranked first in every list and scores stay in
+// [0,1] like the similarity scores they replace.
+func FuseRRF(k int, lists ...RankedList) []Document {
+	if k <= 0 {
+		k = DefaultRRFK
+	}
+
+	scores := map[string]float64{}
+	docs := map[string]Document{}
+	var order []string
+	var best float64
+
+	for _, list := range lists {
+		if list.Weight == 0 {
+			continue
+		}
+		best += list.Weight / float64(k+1)
+		for rank, d := range list.Docs {
+			if _, ok := docs[d.ID]; !ok {
+				docs[d.ID] = d
+				order = append(order, d.ID)
+			}
+			scores[d.ID] += list.Weight / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]Document, len(order))
+	for i, id := range order {
+		d := docs[id]
+		d.Score = scores[id]
+		if best > 0 {
+			d.Score /= best
+		}
+		fused[i] = d
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool { return fused[i].Score > fused[j].Score })
+	return fused
+}
+
+// ================================
+// Hybrid Search
+// ================================
+
+type HybridOptions struct {
+	SearchOptions
+
+	// Weights for the two rankings; both zero means 1/1. Setting one to
+	// zero turns that retriever off.
+	SemanticWeight float64
+	LexicalWeight  float64
+
+	RRFK       int
+	Candidates int // depth fetched from each retriever, default 4*Limit
+}
+
+// HybridSearch runs a semantic search and, when the store supports it, a
+// lexical search for the same query and fuses the two with RRF. MinScore
+// applies to the semantic side only; lexical hits have no comparable score.
+func HybridSearch(ctx context.Context, store Store, query string, vec []float32, opts HybridOptions) ([]Document, error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	semW, lexW := opts.SemanticWeight, opts.LexicalWeight
+	if semW == 0 && lexW == 0 {
+		semW, lexW = 1, 1
+	}
+
+	depth := opts.Candidates
+	if depth <= 0 {
+		depth = 4 * limit
+	}
+
+	candidateOpts := opts.SearchOptions
+	candidateOpts.Limit = depth
+
+	var lists []RankedList
+
+	if semW != 0 {
+		if len(vec) == 0 {
+			return nil, errors.New("empty query vector")
+		}
+		docs, err := store.Search(ctx, vec, candidateOpts)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, RankedList{Docs: docs, Weight: semW})
+	}
+
+	if ts, ok := store.(TextSearcher); ok && lexW != 0 && len(Tokenize(query)) > 0 {
+		lexOpts := candidateOpts
+		lexOpts.MinScore = 0
+
+		docs, err := ts.TextSearch(ctx, query, lexOpts)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, RankedList{Docs: docs, Weight: lexW})
+	}
+
+	fused := FuseRRF(opts.RRFK, lists...)
+	if len(fused) > limit {
+		fused = fused[:limit]
+	}
+	return fused, nil
+}
@@ -0,0 +1,49 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuseRRFNormalized(t *testing.T) {
+	a, b, c := Document{ID: "a"}, Document{ID: "b"}, Document{ID: "c"}
+
+	cases := []struct {
+		name  string
+		lists []RankedList
+		want  map[string]float64
+	}{
+		{
+			"single candidate",
+			[]RankedList{{Docs: []Document{a}, Weight: 1}},
+			map[string]float64{"a": 1},
+		},
+		{
+			"first in both lists",
+			[]RankedList{{Docs: []Document{a, b}, Weight: 1}, {Docs: []Document{a}, Weight: 1}},
+			map[string]float64{"a": 1, "b": 0.5 * 61.0 / 62},
+		},
+		{
+			// a paraphrase the keyword search misses keeps its semantic rank
+			"semantic only",
+			[]RankedList{{Docs: []Document{c}, Weight: 1}, {Docs: []Document{b}, Weight: 1}},
+			map[string]float64{"c": 0.5, "b": 0.5},
+		},
+		{
+			"weighted",
+			[]RankedList{{Docs: []Document{a}, Weight: 3}, {Docs: []Document{b}, Weight: 1}},
+			map[string]float64{"a": 0.75, "b": 0.25},
+		},
+	}
+	for _, tc := range cases {
+		got := FuseRRF(0, tc.lists...)
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: got %d documents, want %d", tc.name, len(got), len(tc.want))
+		}
+		for _, d := range got {
+			if math.Abs(d.Score-tc.want[d.ID]) > 1e-9 {
+				t.Errorf("%s: %s scored %v, want %v", tc.name, d.ID, d.Score, tc.want[d.ID])
+			}
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package vector
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"quavixAI/pkg/response"
+)
+
+// ================================
+// Handler
+// ================================
+
+type HandlerConfig struct {
+	Store       Store
+	Embedder    Embedder
+	Collections *CollectionManager
+	Reembedder  *Reembedder
+	Retention   *RetentionWorker
+}
+
+type Handler struct {
+	store       Store
+	embed       Embedder
+	collections *CollectionManager // nil when the backend is not pgvector
+	reembed     *Reembedder
+	retention   *RetentionWorker
+}
+
+func NewHandler(cfg HandlerConfig) *Handler {
+	return &Handler{
+		store:       cfg.Store,
+		embed:       cfg.Embedder,
+		collections: cfg.Collections,
+		reembed:     cfg.Reembedder,
+		retention:   cfg.Retention,
+	}
+}
+
+// ================================
+// Bulk Import Endpoint
+// ================================
+
+// Import accepts a JSONL body (one ImportRecord per line) and streams
+// ImportProgress objects as NDJSON, one per stored batch, ending with
+// a record where done=true. ?collection= targets a named collection.
+func (h *Handler) Import(c response.Context) error {
+	q := c.Request.URL.Query()
+
+	store := h.store
+	if name := q.Get("collection"); name != "" {
+		if h.collections == nil {
+			return c.JSON(http.StatusBadRequest, response.Error("collections are not supported by this vector backend"))
+		}
+		cs, err := h.collections.Store(c.Context(), name)
+		if err != nil {
+			return c.JSON(collectionStatus(err), response.Error(err.Error()))
+		}
+		store = cs
+	}
+
+	c.Writer.Header().Set("Content-Type", "application/x-ndjson")
+	c.Writer.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	flusher, _ := c.Writer.(http.Flusher)
+
+	report := func(p ImportProgress) {
+		_ = enc.Encode(p)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	final, err := NewImporter(store, h.embed).Import(c.Context(), c.Request.Body, q.Get("namespace"), report)
+	if err != nil {
+		final.Error = err.Error()
+	}
+	final.Done = true
+	report(final)
+
+	return nil
+}
+
+// ================================
+// Collection Endpoints
+// ================================
+
+func (h *Handler) CreateCollection(c response.Context) error {
+	if h.collections == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("collections are not supported by this vector backend"))
+	}
+
+	var req Collection
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+
+	col, err := h.collections.Create(c.Context(), req)
+	if err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusCreated, response.Success(col))
+}
+
+func (h *Handler) ListCollections(c response.Context) error {
+	if h.collections == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("collections are not supported by this vector backend"))
+	}
+
+	cols, err := h.collections.List(c.Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(cols))
+}
+
+func (h *Handler) GetCollection(c response.Context) error {
+	if h.collections == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("collections are not supported by this vector backend"))
+	}
+
+	info, err := h.collections.Inspect(c.Context(), c.Request.PathValue("name"))
+	if err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(info))
+}
+
+func (h *Handler) DropCollection(c response.Context) error {
+	if h.collections == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("collections are not supported by this vector backend"))
+	}
+
+	name := c.Request.PathValue("name")
+	if err := h.collections.Drop(c.Context(), name); err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(map[string]interface{}{
+		"dropped": name,
+	}))
+}
+
+// ================================
+// Re-embedding Endpoints (admin)
+// ================================
+
+type ReembedRequest struct {
+	Collection string `json:"collection"`
+}
+
+func (h *Handler) StartReembed(c response.Context) error {
+	if h.reembed == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("re-embedding is not configured"))
+	}
+
+	var req ReembedRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, response.Error("invalid request body"))
+	}
+	if req.Collection == "" {
+		req.Collection = DefaultCollection
+	}
+
+	job, err := h.reembed.Start(c.Context(), req.Collection)
+	if err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusAccepted, response.Success(job))
+}
+
+func (h *Handler) ListReembeds(c response.Context) error {
+	if h.reembed == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("re-embedding is not configured"))
+	}
+
+	jobs, err := h.reembed.List(c.Context(), c.Request.URL.Query().Get("collection"))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(jobs))
+}
+
+func (h *Handler) GetReembed(c response.Context) error {
+	if h.reembed == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("re-embedding is not configured"))
+	}
+
+	job, err := h.reembed.Get(c.Context(), c.Request.PathValue("id"))
+	if err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(job))
+}
+
+func (h *Handler) ResumeReembed(c response.Context) error {
+	if h.reembed == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("re-embedding is not configured"))
+	}
+
+	job, err := h.reembed.Resume(c.Context(), c.Request.PathValue("id"))
+	if err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusAccepted, response.Success(job))
+}
+
+func (h *Handler) CancelReembed(c response.Context) error {
+	if h.reembed == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("re-embedding is not configured"))
+	}
+
+	id := c.Request.PathValue("id")
+	if err := h.reembed.Cancel(c.Context(), id); err != nil {
+		return c.JSON(collectionStatus(err), response.Error(err.Error()))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(map[string]interface{}{
+		"cancelled": id,
+	}))
+}
+
+// ================================
+// Retention Endpoints (admin)
+// ================================
+
+// RetentionReport returns the last compaction report.
+func (h *Handler) RetentionReport(c response.Context) error {
+	if h.retention == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("retention is not configured"))
+	}
+
+	report := h.retention.Last()
+	if report == nil {
+		return c.JSON(http.StatusNotFound, response.Error("no compaction has run yet"))
+	}
+
+	return c.JSON(http.StatusOK, response.Success(report))
+}
+
+// RunRetention compacts immediately and returns the report.
+func (h *Handler) RunRetention(c response.Context) error {
+	if h.retention == nil {
+		return c.JSON(http.StatusBadRequest, response.Error("retention is not configured"))
+	}
+
+	report := h.retention.RunOnce(c.Context())
+	return c.JSON(http.StatusOK, response.Success(report))
+}
+
+func collectionStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrCollectionNotFound), errors.Is(err, ErrReembedNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrCollectionExists), errors.Is(err, ErrReembedActive),
+		errors.Is(err, ErrReembedNotRunning), errors.Is(err, ErrAlreadyEmbedded):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
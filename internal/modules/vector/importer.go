@@ -0,0 +1,159 @@
+package vector
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ================================
+// Bulk Import (JSONL)
+// ================================
+
+const (
+	DefaultImportBatchSize = 500
+
+	maxImportLine   = 16 << 20 // one line may carry a full embedding
+	maxImportErrors = 100
+)
+
+// ImportRecord is one JSONL line. Vector is optional when an Embedder
+// is configured; Content is then embedded on the fly.
+type ImportRecord struct {
+	ID        string                 `json:"id"`
+	Namespace string                 `json:"namespace"`
+	Content   string                 `json:"content"`
+	Vector    []float32              `json:"vector"`
+	Meta      map[string]interface{} `json:"meta"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportProgress struct {
+	Lines    int           `json:"lines"`
+	Imported int           `json:"imported"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors,omitempty"`
+	Done     bool          `json:"done"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type Importer struct {
+	store     Store
+	embed     Embedder
+	batchSize int
+}
+
+func NewImporter(store Store, embed Embedder) *Importer {
+	return &Importer{
+		store:     store,
+		embed:     embed,
+		batchSize: DefaultImportBatchSize,
+	}
+}
+
+// Import reads JSONL documents from r and writes them with StoreBatch.
+// Malformed lines are counted and reported but do not stop the import;
+// a store failure does. progress is called after every flushed batch.
+func (im *Importer) Import(ctx context.Context, r io.Reader, namespace string, progress func(ImportProgress)) (ImportProgress, error) {
+	var p ImportProgress
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+
+	batch := make([]Document, 0, im.batchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := im.store.StoreBatch(ctx, batch); err != nil {
+			return err
+		}
+		p.Imported += len(batch)
+		batch = batch[:0]
+		if progress != nil {
+			progress(p)
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		p.Lines++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		doc, err := im.parse(ctx, line, namespace)
+		if err != nil {
+			p.Failed++
+			if len(p.Errors) < maxImportErrors {
+				p.Errors = append(p.Errors, ImportError{Line: p.Lines, Error: err.Error()})
+			}
+			continue
+		}
+
+		batch = append(batch, doc)
+		if len(batch) >= im.batchSize {
+			if err := flush(); err != nil {
+				return p, err
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return p, fmt.Errorf("read failed at line %d: %w", p.Lines+1, err)
+	}
+
+	if err := flush(); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+func (im *Importer) parse(ctx context.Context, line, namespace string) (Document, error) {
+	var rec ImportRecord
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return Document{}, fmt.Errorf("invalid json: %w", err)
+	}
+
+	if rec.ID == "" {
+		return Document{}, errors.New("missing document id")
+	}
+
+	if len(rec.Vector) == 0 {
+		if rec.Content == "" {
+			return Document{}, errors.New("missing content and vector")
+		}
+		if im.embed == nil {
+			return Document{}, errors.New("missing vector and no embedder configured")
+		}
+		vec, err := im.embed.Embed(ctx, rec.Content)
+		if err != nil {
+			return Document{}, fmt.Errorf("embedding failed: %w", err)
+		}
+		rec.Vector = vec
+	}
+
+	if rec.Namespace == "" {
+		rec.Namespace = namespace
+	}
+
+	return Document{
+		ID:        rec.ID,
+		Namespace: rec.Namespace,
+		Content:   rec.Content,
+		Vector:    rec.Vector,
+		Meta:      rec.Meta,
+	}, nil
+}
@@ -0,0 +1,115 @@
+package vector
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// ================================
+// Lexical Search
+// ================================
+
+// TextSearcher is implemented by stores that can rank documents by the
+// words they contain (tsvector for pgvector, BM25 for in-memory backends).
+// Exact identifiers such as error codes and service names are found here
+// even when their embeddings are not close to the query.
+type TextSearcher interface {
+	TextSearch(ctx context.Context, query string, opts SearchOptions) ([]Document, error)
+}
+
+// ================================
+// Tokenizer
+// ================================
+
+// Tokenize lowercases and splits on anything that is not a letter, digit
+// or one of "_-." so identifiers like "ERR_CONN_RESET" or "payment-svc"
+// survive as single terms.
+func Tokenize(s string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.'
+	})
+
+	tokens := fields[:0]
+	for _, f := range fields {
+		f = strings.Trim(f, "-.")
+		if f != "" {
+			tokens = append(tokens, f)
+		}
+	}
+	return tokens
+}
+
+func termFrequencies(s string) (map[string]int, int) {
+	tokens := Tokenize(s)
+	tf := make(map[string]int, len(tokens))
+	for _, t := range tokens {
+		tf[t]++
+	}
+	return tf, len(tokens)
+}
+
+// ================================
+// BM25
+// ================================
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+type bm25Doc struct {
+	terms  map[string]int
+	length int
+}
+
+// bm25 scores every document against the query terms (Okapi BM25 with
+// the usual k1/b defaults). Scores are returned in document order.
+func bm25(query []string, docs []bm25Doc) []float64 {
+	scores := make([]float64, len(docs))
+	if len(docs) == 0 {
+		return scores
+	}
+
+	var total int
+	for _, d := range docs {
+		total += d.length
+	}
+	avgLen := float64(total) / float64(len(docs))
+	if avgLen == 0 {
+		return scores
+	}
+
+	seen := make(map[string]bool, len(query))
+	for _, term := range query {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		df := 0
+		for _, d := range docs {
+			if d.terms[term] > 0 {
+				df++
+			}
+		}
+		if df == 0 {
+			continue
+		}
+
+		n := float64(len(docs))
+		idf := math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+
+		for i, d := range docs {
+			tf := float64(d.terms[term])
+			if tf == 0 {
+				continue
+			}
+			norm := tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(d.length)/avgLen))
+			scores[i] += idf * norm
+		}
+	}
+
+	return scores
+}
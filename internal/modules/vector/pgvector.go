@@ -3,48 +3,53 @@ package vector
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
-)
-
-// ================================
-// Data Structures
-// ================================
+	"sync"
+	"time"
 
-type Document struct {
-	ID      string            `json:"id"`
-	Content string            `json:"content"`
-	Vector  []float32         `json:"vector"`
-	Meta    map[string]string `json:"meta"`
-}
-
-// ================================
-// Store Interface
-// ================================
-
-type Store interface {
-	Init(ctx context.Context) error
-	Store(ctx context.Context, doc Document) error
-	Search(ctx context.Context, vector []float32, limit int) ([]Document, error)
-	Delete(ctx context.Context, id string) error
-}
+	"github.com/lib/pq"
+)
 
 // ================================
 // PgVector Store
 // ================================
 
 type PgVectorStore struct {
-	db        *sql.DB
+	db     *sql.DB
+	table  string
+	metric Metric
+	index  IndexType
+	params IndexParams
+
+	mu        sync.RWMutex // a re-embedding swap changes these
 	dimension int
-	table     string
+	model     string
 }
 
+// NewPgVectorStore opens the default collection (table vector_memory).
 func NewPgVectorStore(db *sql.DB, dimension int) *PgVectorStore {
+	return NewPgVectorCollection(db, Collection{
+		Name:      DefaultCollection,
+		Dimension: dimension,
+	})
+}
+
+// NewPgVectorCollection opens the table backing a named collection.
+func NewPgVectorCollection(db *sql.DB, c Collection) *PgVectorStore {
+	c.defaults()
 	return &PgVectorStore{
 		db:        db,
-		dimension: dimension,
-		table:     "vector_memory",
+		dimension: c.Dimension,
+		table:     c.Table(),
+		metric:    c.Metric,
+		model:     c.Model,
+		index:     c.Index,
+		params:    c.Params,
 	}
 }
 
@@ -58,15 +63,34 @@ func (p *PgVectorStore) Init(ctx context.Context) error {
 
 		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
 			id TEXT PRIMARY KEY,
+			namespace TEXT NOT NULL DEFAULT '',
 			content TEXT,
 			embedding VECTOR(%d),
 			metadata JSONB,
+			embedding_model TEXT NOT NULL DEFAULT '',
+			embedding_dim INT,
 			created_at TIMESTAMPTZ DEFAULT NOW()
 		);`, p.table, p.dimension),
 
-		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx
-			ON %s USING ivfflat (embedding vector_l2_ops)
-			WITH (lists = 100);`, p.table, p.table),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS namespace TEXT NOT NULL DEFAULT '';`, p.table),
+
+		// rows written before stamping keep an empty model (unknown)
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS embedding_model TEXT NOT NULL DEFAULT '';`, p.table),
+
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS embedding_dim INT;`, p.table),
+
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_namespace_idx ON %s (namespace);`, p.table, p.table),
+
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_metadata_idx ON %s USING GIN (metadata jsonb_path_ops);`, p.table, p.table),
+
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS content_tsv tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED;`, p.table),
+
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_content_tsv_idx ON %s USING GIN (content_tsv);`, p.table, p.table),
+	}
+
+	if idx := p.indexSQL(); idx != "" {
+		queries = append(queries, idx)
 	}
 
 	for _, q := range queries {
@@ -78,6 +102,39 @@ func (p *PgVectorStore) Init(ctx context.Context) error {
 	return nil
 }
 
+func (p *PgVectorStore) indexSQL() string {
+	opclass := map[Metric]string{
+		MetricL2:           "vector_l2_ops",
+		MetricCosine:       "vector_cosine_ops",
+		MetricInnerProduct: "vector_ip_ops",
+	}[p.metric]
+
+	switch p.index {
+	case IndexHNSW:
+		return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx
+			ON %s USING hnsw (embedding %s)
+			WITH (m = %d, ef_construction = %d);`, p.table, p.table, opclass, p.params.M, p.params.EfConstruction)
+	case IndexIVFFlat:
+		return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx
+			ON %s USING ivfflat (embedding %s)
+			WITH (lists = %d);`, p.table, p.table, opclass, p.params.Lists)
+	default:
+		return ""
+	}
+}
+
+// searchSettings returns the per-query index knobs, applied with SET LOCAL.
+func (p *PgVectorStore) searchSettings() string {
+	switch {
+	case p.index == IndexHNSW && p.params.EfSearch > 0:
+		return fmt.Sprintf("SET LOCAL hnsw.ef_search = %d;", p.params.EfSearch)
+	case p.index == IndexIVFFlat && p.params.Probes > 0:
+		return fmt.Sprintf("SET LOCAL ivfflat.probes = %d;", p.params.Probes)
+	default:
+		return ""
+	}
+}
+
 // ================================
 // Store Document
 // ================================
@@ -90,42 +147,215 @@ func (p *PgVectorStore) Store(ctx context.Context, doc Document) error {
 		return errors.New("missing embedding vector")
 	}
 
-	vecStr := vectorToSQL(doc.Vector)
+	metaJSON, err := encodeMeta(doc.Meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.ExecContext(ctx, p.upsertSQL(), p.upsertArgs(doc, metaJSON)...)
+	return err
+}
 
-	query := fmt.Sprintf(`INSERT INTO %s (id, content, embedding, metadata)
-		VALUES ($1, $2, %s, $3)
+// upsertSQL writes one document; created_at is kept on conflict and
+// defaults to NOW() when the document carries none.
+func (p *PgVectorStore) upsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s (id, namespace, content, embedding, metadata, embedding_model, embedding_dim, created_at)
+		VALUES ($1, $2, $3, $4::vector, $5::jsonb, $6, $7, COALESCE($8::timestamptz, NOW()))
 		ON CONFLICT (id)
 		DO UPDATE SET
+			namespace = EXCLUDED.namespace,
 			content = EXCLUDED.content,
 			embedding = EXCLUDED.embedding,
-			metadata = EXCLUDED.metadata;`, p.table, vecStr)
+			metadata = EXCLUDED.metadata,
+			embedding_model = EXCLUDED.embedding_model,
+			embedding_dim = EXCLUDED.embedding_dim;`, p.table)
+}
+
+func (p *PgVectorStore) upsertArgs(doc Document, metaJSON string) []interface{} {
+	return []interface{}{
+		doc.ID, doc.Namespace, doc.Content, encodeVector(doc.Vector), metaJSON,
+		p.modelOf(doc), len(doc.Vector), nullTime(doc.CreatedAt),
+	}
+}
 
-	metaJSON := mapToJSON(doc.Meta)
+func (p *PgVectorStore) modelOf(doc Document) string {
+	if doc.Model != "" {
+		return doc.Model
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.model
+}
 
-	_, err := p.db.ExecContext(ctx, query, doc.ID, doc.Content, metaJSON)
-	return err
+// adopt switches the store to a collection's current model and
+// dimension, after its table was re-embedded.
+func (p *PgVectorStore) adopt(c Collection) {
+	p.mu.Lock()
+	p.model = c.Model
+	p.dimension = c.Dimension
+	p.mu.Unlock()
+}
+
+// ================================
+// Batch Upsert
+// ================================
+
+// StoreBatch streams documents into a temporary staging table with COPY
+// and merges them into the collection with one INSERT ... ON CONFLICT.
+// When an id repeats inside the batch the last occurrence wins.
+func (p *PgVectorStore) StoreBatch(ctx context.Context, docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	staging := p.table + "_staging"
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE TEMP TABLE %s (
+			ord BIGINT,
+			id TEXT,
+			namespace TEXT,
+			content TEXT,
+			embedding TEXT,
+			metadata TEXT,
+			embedding_model TEXT,
+			embedding_dim INT,
+			created_at TIMESTAMPTZ
+		) ON COMMIT DROP;`, staging)); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn(staging,
+		"ord", "id", "namespace", "content", "embedding", "metadata", "embedding_model", "embedding_dim", "created_at"))
+	if err != nil {
+		return err
+	}
+
+	for i, doc := range docs {
+		if doc.ID == "" {
+			stmt.Close()
+			return fmt.Errorf("document %d: missing document id", i)
+		}
+		if len(doc.Vector) == 0 {
+			stmt.Close()
+			return fmt.Errorf("document %d (%s): missing embedding vector", i, doc.ID)
+		}
+
+		metaJSON, err := encodeMeta(doc.Meta)
+		if err != nil {
+			stmt.Close()
+			return fmt.Errorf("document %d (%s): %w", i, doc.ID, err)
+		}
+
+		if _, err := stmt.ExecContext(ctx, i, doc.ID, doc.Namespace, doc.Content, encodeVector(doc.Vector), metaJSON,
+			p.modelOf(doc), len(doc.Vector), nullTime(doc.CreatedAt)); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+
+	// flush the COPY buffer
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	merge := fmt.Sprintf(`INSERT INTO %s (id, namespace, content, embedding, metadata, embedding_model, embedding_dim, created_at)
+		SELECT DISTINCT ON (id) id, namespace, content, embedding::vector, metadata::jsonb,
+			embedding_model, embedding_dim, COALESCE(created_at, NOW())
+		FROM %s
+		ORDER BY id, ord DESC
+		ON CONFLICT (id)
+		DO UPDATE SET
+			namespace = EXCLUDED.namespace,
+			content = EXCLUDED.content,
+			embedding = EXCLUDED.embedding,
+			metadata = EXCLUDED.metadata,
+			embedding_model = EXCLUDED.embedding_model,
+			embedding_dim = EXCLUDED.embedding_dim;`, p.table, staging)
+
+	if _, err := tx.ExecContext(ctx, merge); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // ================================
 // Similarity Search
 // ================================
 
-func (p *PgVectorStore) Search(ctx context.Context, vector []float32, limit int) ([]Document, error) {
+func (p *PgVectorStore) Search(ctx context.Context, vector []float32, opts SearchOptions) ([]Document, error) {
 	if len(vector) == 0 {
 		return nil, errors.New("empty query vector")
 	}
+	limit := opts.Limit
 	if limit <= 0 {
 		limit = 5
 	}
 
-	vecStr := vectorToSQL(vector)
+	metric := opts.Metric
+	if metric == "" {
+		metric = p.metric
+	}
+	op, err := distanceOperator(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{encodeVector(vector)}
+	distExpr := fmt.Sprintf("(embedding %s $1::vector)", op)
 
-	query := fmt.Sprintf(`SELECT id, content, metadata
+	where, args, err := filterToSQL(opts.Filter, args)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MinScore != 0 {
+		args = append(args, maxDistance(metric, opts.MinScore))
+		where = append(where, fmt.Sprintf("%s <= $%d", distExpr, len(args)))
+	}
+
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	vecCol := "NULL"
+	if opts.IncludeVectors {
+		vecCol = "embedding::text"
+	}
+
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`SELECT id, namespace, content, metadata, created_at, embedding_model, embedding_dim, %s AS distance, %s
 		FROM %s
-		ORDER BY embedding <-> %s
-		LIMIT %d;`, p.table, vecStr, limit)
+		%s
+		ORDER BY distance
+		LIMIT $%d;`, distExpr, vecCol, p.table, whereSQL, len(args))
+
+	var q queryer = p.db
+	if settings := p.searchSettings(); settings != "" {
+		tx, err := p.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
 
-	rows, err := p.db.QueryContext(ctx, query)
+		if _, err := tx.ExecContext(ctx, settings); err != nil {
+			return nil, err
+		}
+		q = tx
+	}
+
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -134,21 +364,117 @@ func (p *PgVectorStore) Search(ctx context.Context, vector []float32, limit int)
 	var results []Document
 
 	for rows.Next() {
-		var id, content string
-		var metaJSON []byte
+		doc, distance, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		doc.Distance = distance
+		doc.Score = Score(metric, distance)
+		results = append(results, doc)
+	}
+
+	return results, rows.Err()
+}
+
+// ================================
+// Full-Text Search
+// ================================
+
+// TextSearch ranks documents by ts_rank_cd over the generated
+// content_tsv column. The 'simple' configuration skips stemming and stop
+// words so identifiers are matched as typed. Any query term matches, as
+// with the BM25 backend; documents holding more of them rank higher.
+func (p *PgVectorStore) TextSearch(ctx context.Context, query string, opts SearchOptions) ([]Document, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty text query")
+	}
+	tsquery := orTSQuery(query)
+	if tsquery == "" {
+		return nil, nil
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+
+	args := []interface{}{tsquery}
 
-		if err := rows.Scan(&id, &content, &metaJSON); err != nil {
+	where, args, err := filterToSQL(opts.Filter, args)
+	if err != nil {
+		return nil, err
+	}
+	where = append([]string{"content_tsv @@ q"}, where...)
+
+	vecCol := "NULL"
+	if opts.IncludeVectors {
+		vecCol = "embedding::text"
+	}
+
+	args = append(args, limit)
+
+	sqlQuery := fmt.Sprintf(`SELECT id, namespace, content, metadata, created_at, embedding_model, embedding_dim, ts_rank_cd(content_tsv, q) AS rank, %s
+		FROM %s, to_tsquery('simple', $1) q
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d;`, vecCol, p.table, strings.Join(where, " AND "), len(args))
+
+	rows, err := p.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Document
+
+	for rows.Next() {
+		doc, rank, err := scanDocument(rows)
+		if err != nil {
 			return nil, err
 		}
+		doc.Score = rank
+		results = append(results, doc)
+	}
+
+	return results, rows.Err()
+}
+
+// orTSQuery ORs the distinct Tokenize terms of query into to_tsquery
+// syntax. Tokens hold only letters, digits and "_-.", so quoting each one
+// keeps it a single operand.
+func orTSQuery(query string) string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range Tokenize(query) {
+		if !seen[t] {
+			seen[t] = true
+			terms = append(terms, "'"+t+"'")
+		}
+	}
+	return strings.Join(terms, " | ")
+}
 
-		results = append(results, Document{
-			ID:      id,
-			Content: content,
-			Meta:    jsonToMap(metaJSON),
-		})
+// ================================
+// Metadata Update
+// ================================
+
+// UpdateMeta merges patch into the metadata of every listed document
+// (top-level keys are replaced).
+func (p *PgVectorStore) UpdateMeta(ctx context.Context, ids []string, patch map[string]interface{}) error {
+	if len(ids) == 0 || len(patch) == 0 {
+		return nil
 	}
 
-	return results, nil
+	patchJSON, err := encodeMeta(patch)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`UPDATE %s
+		SET metadata = COALESCE(metadata, '{}'::jsonb) || $2::jsonb
+		WHERE id = ANY($1);`, p.table)
+
+	_, err = p.db.ExecContext(ctx, query, pq.Array(ids), patchJSON)
+	return err
 }
 
 // ================================
@@ -161,53 +487,280 @@ func (p *PgVectorStore) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+// DeleteWhere removes every document matching f. An empty filter is
+// rejected rather than truncating the table.
+func (p *PgVectorStore) DeleteWhere(ctx context.Context, f Filter) (int64, error) {
+	if f.IsEmpty() {
+		return 0, errors.New("refusing to delete with an empty filter")
+	}
+
+	where, args, err := filterToSQL(f, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE %s;`, p.table, strings.Join(where, " AND "))
+
+	res, err := p.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ================================
+// Retention
+// ================================
+
+// Compact enforces p with one DELETE per rule, in the order age,
+// per-user cap, importance.
+func (p *PgVectorStore) Compact(ctx context.Context, policy RetentionPolicy, now time.Time) (EvictionStats, error) {
+	stats := EvictionStats{Type: policy.Type}
+	if err := policy.Validate(); err != nil {
+		return stats, err
+	}
+
+	typeJSON, err := json.Marshal(map[string]string{metaType: policy.Type})
+	if err != nil {
+		return stats, err
+	}
+
+	exec := func(query string, args ...interface{}) (int64, error) {
+		res, err := p.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	if policy.MaxAge > 0 {
+		stats.Expired, err = exec(fmt.Sprintf(`DELETE FROM %s
+			WHERE metadata @> $1::jsonb AND created_at < $2;`, p.table),
+			string(typeJSON), now.Add(-policy.MaxAge))
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if policy.MaxPerUser > 0 {
+		stats.OverLimit, err = exec(fmt.Sprintf(`DELETE FROM %s WHERE id IN (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (
+					PARTITION BY metadata->>'%s'
+					ORDER BY created_at DESC, id DESC
+				) AS rn
+				FROM %s
+				WHERE metadata @> $1::jsonb
+			) ranked
+			WHERE rn > $2
+		);`, p.table, metaUser, p.table), string(typeJSON), policy.MaxPerUser)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	if policy.MinImportance > 0 {
+		stats.LowImportance, err = exec(fmt.Sprintf(`DELETE FROM %s
+			WHERE metadata @> $1::jsonb
+			AND CASE WHEN jsonb_typeof(metadata->'%s') = 'number'
+				THEN (metadata->>'%s')::float8 < $2
+				ELSE false END;`, p.table, metaImportance, metaImportance),
+			string(typeJSON), policy.MinImportance)
+		if err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
 // ================================
 // Helpers
 // ================================
 
-func vectorToSQL(vec []float32) string {
-	vals := make([]string, len(vec))
-	for i, v := range vec {
-		vals[i] = fmt.Sprintf("%f", v)
+func distanceOperator(metric Metric) (string, error) {
+	switch metric {
+	case MetricL2:
+		return "<->", nil
+	case MetricCosine:
+		return "<=>", nil
+	case MetricInnerProduct:
+		return "<#>", nil
+	default:
+		return "", errors.New("unsupported distance metric: " + string(metric))
 	}
-	return "ARRAY[" + strings.Join(vals, ",") + "]"
 }
 
-func mapToJSON(m map[string]string) string {
-	if m == nil {
-		return "{}"
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// scanDocument reads the common result shape
+// (id, namespace, content, metadata, created_at, embedding_model,
+// embedding_dim, <value>, <vector text>) and returns the document
+// together with the ranking value.
+func scanDocument(rows *sql.Rows) (Document, float64, error) {
+	var id, namespace, content, model string
+	var metaJSON []byte
+	var createdAt sql.NullTime
+	var dim sql.NullInt64
+	var value float64
+	var vecText sql.NullString
+
+	if err := rows.Scan(&id, &namespace, &content, &metaJSON, &createdAt, &model, &dim, &value, &vecText); err != nil {
+		return Document{}, 0, err
+	}
+
+	meta, err := decodeMeta(metaJSON)
+	if err != nil {
+		return Document{}, 0, err
+	}
+
+	doc := Document{
+		ID:        id,
+		Namespace: namespace,
+		Content:   content,
+		Meta:      meta,
+		CreatedAt: createdAt.Time,
+		Model:     model,
+		Dimension: int(dim.Int64),
+	}
+
+	if vecText.Valid {
+		vec, err := parseVector(vecText.String)
+		if err != nil {
+			return Document{}, 0, err
+		}
+		doc.Vector = vec
+	}
+
+	return doc, value, nil
+}
+
+// filterToSQL appends one predicate per condition, binding every value
+// (and JSON key) as a parameter numbered after the existing args.
+// Equality uses JSONB containment so values of any JSON type match and
+// the GIN index on metadata applies.
+func filterToSQL(f Filter, args []interface{}) ([]string, []interface{}, error) {
+	var where []string
+
+	if f.Namespace != "" || f.ExactNamespace {
+		args = append(args, f.Namespace)
+		where = append(where, fmt.Sprintf("namespace = $%d", len(args)))
+	}
+
+	if len(f.Equals) > 0 {
+		contains, err := json.Marshal(f.Equals)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid metadata filter: %w", err)
+		}
+		args = append(args, string(contains))
+		where = append(where, fmt.Sprintf("metadata @> $%d::jsonb", len(args)))
+	}
+
+	keys := make([]string, 0, len(f.In))
+	for k := range f.In {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		vals := make([]string, len(f.In[k]))
+		for i, v := range f.In[k] {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid metadata filter for %q: %w", k, err)
+			}
+			vals[i] = string(b)
+		}
+		args = append(args, k, pq.Array(vals))
+		where = append(where, fmt.Sprintf("metadata->($%d::text) = ANY($%d::jsonb[])", len(args)-1, len(args)))
+	}
+
+	notKeys := make([]string, 0, len(f.NotEquals))
+	for k := range f.NotEquals {
+		notKeys = append(notKeys, k)
 	}
+	sort.Strings(notKeys)
+
+	for _, k := range notKeys {
+		pair, err := json.Marshal(map[string]interface{}{k: f.NotEquals[k]})
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid metadata filter for %q: %w", k, err)
+		}
+		args = append(args, string(pair))
+		where = append(where, fmt.Sprintf("NOT (metadata @> $%d::jsonb)", len(args)))
+	}
+
+	return where, args, nil
+}
+
+// encodeVector renders pgvector's text input format, e.g. "[1,2.5,3]".
+// Shortest round-trip formatting keeps full float32 precision.
+func encodeVector(vec []float32) string {
 	var b strings.Builder
-	b.WriteString("{")
-	first := true
-	for k, v := range m {
-		if !first {
-			b.WriteString(",")
+	b.Grow(len(vec) * 10)
+	b.WriteByte('[')
+	for i, v := range vec {
+		if i > 0 {
+			b.WriteByte(',')
 		}
-		first = false
-		b.WriteString(fmt.Sprintf("\"%s\":\"%s\"", k, v))
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
 	}
-	b.WriteString("}")
+	b.WriteByte(']')
 	return b.String()
 }
 
-func jsonToMap(b []byte) map[string]string {
-	res := map[string]string{}
-	if len(b) == 0 {
-		return res
-	}
-	s := strings.Trim(string(b), "{}")
+// parseVector reads pgvector's text representation, e.g. "[1,2.5,3]".
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
 	if s == "" {
-		return res
+		return nil, nil
 	}
+
 	parts := strings.Split(s, ",")
-	for _, p := range parts {
-		kv := strings.SplitN(p, ":", 2)
-		if len(kv) == 2 {
-			k := strings.Trim(kv[0], "\"")
-			v := strings.Trim(kv[1], "\"")
-			res[k] = v
+	vec := make([]float32, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", part, err)
 		}
+		vec[i] = float32(f)
+	}
+	return vec, nil
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func encodeMeta(m map[string]interface{}) (string, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("invalid metadata: %w", err)
+	}
+	return string(b), nil
+}
+
+func decodeMeta(b []byte) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	if len(b) == 0 {
+		return res, nil
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return nil, fmt.Errorf("invalid metadata json: %w", err)
+	}
+	if res == nil {
+		// a JSON null column; callers write into Meta
+		res = map[string]interface{}{}
 	}
-	return res
+	return res, nil
 }
@@ -0,0 +1,154 @@
+package vector
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzVectorRoundTrip(f *testing.F) {
+	f.Add(float32(0), float32(1), float32(-2.5))
+	f.Add(float32(math.SmallestNonzeroFloat32), float32(math.MaxFloat32), float32(1e-7))
+
+	f.Fuzz(func(t *testing.T, a, b, c float32) {
+		vec := []float32{a, b, c}
+		for _, v := range vec {
+			// pgvector rejects these
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				t.Skip()
+			}
+		}
+
+		got, err := parseVector(encodeVector(vec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, vec) {
+			t.Fatalf("round trip of %v gave %v", vec, got)
+		}
+	})
+}
+
+func FuzzMetaRoundTrip(f *testing.F) {
+	f.Add(`{"type":"question","score":3,"tags":["a","b"],"nested":{"x":null}}`)
+	f.Add(`{"":"","\u0000":1e300}`)
+
+	f.Fuzz(func(t *testing.T, raw string) {
+		meta, err := decodeMeta([]byte(raw))
+		if err != nil {
+			t.Skip()
+		}
+		if meta == nil {
+			t.Fatalf("decoding %s gave a nil map", raw)
+		}
+
+		enc, err := encodeMeta(meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := decodeMeta([]byte(enc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(meta, again) {
+			t.Fatalf("round trip of %s gave %v, want %v", raw, again, meta)
+		}
+	})
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// FuzzFilterToSQL checks that every key and value reaches the query as a
+// bound parameter, numbered after the existing args, and that a document
+// read back from the stored metadata matches the filter that selects it.
+func FuzzFilterToSQL(f *testing.F) {
+	f.Add("type", "question", "tags", "a", 2.0, "ns")
+	f.Add("a'b", "x\"; DROP TABLE t; --", "$1", "", -0.5, "")
+
+	f.Fuzz(func(t *testing.T, eqKey, eqValue, inKey, inValue string, num float64, namespace string) {
+		if math.IsNaN(num) || math.IsInf(num, 0) {
+			t.Skip()
+		}
+		for _, s := range []string{eqKey, eqValue, inKey, inValue} {
+			// JSON replaces invalid UTF-8, so such keys cannot round-trip
+			if !utf8.ValidString(s) {
+				t.Skip()
+			}
+		}
+
+		filter := Filter{
+			Namespace: namespace,
+			Equals:    map[string]interface{}{eqKey: eqValue},
+			In:        map[string][]interface{}{inKey: {inValue, num}},
+			NotEquals: map[string]interface{}{eqKey + "!": num},
+		}
+
+		existing := []interface{}{"vec", 10}
+		where, args, err := filterToSQL(filter, existing)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sql := strings.Join(where, " AND ")
+		for _, m := range placeholder.FindAllStringSubmatch(sql, -1) {
+			n, _ := strconv.Atoi(m[1])
+			if n <= len(existing) || n > len(args) {
+				t.Fatalf("placeholder $%d outside new args %d..%d in %q", n, len(existing)+1, len(args), sql)
+			}
+		}
+		for _, v := range args[len(existing):] {
+			if s, ok := v.(string); ok && len(s) > 3 && strings.Contains(sql, s) {
+				t.Fatalf("argument %q inlined into %q", s, sql)
+			}
+		}
+
+		stored := map[string]interface{}{eqKey: eqValue}
+		if inKey != eqKey {
+			stored[inKey] = num
+		}
+		enc, err := encodeMeta(stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta, err := decodeMeta([]byte(enc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc := Document{Namespace: namespace, Meta: meta}
+		if inKey == eqKey {
+			// the In list holds eqValue only when the fuzzer repeats it
+			filter.In = map[string][]interface{}{inKey: {eqValue}}
+		}
+		if eqKey+"!" == inKey {
+			t.Skip()
+		}
+		if !filter.Match(doc) {
+			raw, _ := json.Marshal(filter)
+			t.Fatalf("document %s does not match filter %s", enc, raw)
+		}
+	})
+}
+
+func TestOrTSQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"payment timeout", "'payment' | 'timeout'"},
+		{"ERR_CONN_RESET on payment-svc", "'err_conn_reset' | 'on' | 'payment-svc'"},
+		{"retry Retry RETRY", "'retry'"},
+		{"it's (a) & b | !c", "'it' | 's' | 'a' | 'b' | 'c'"},
+		{"?!", ""},
+	}
+	for _, tt := range tests {
+		if got := orTSQuery(tt.query); got != tt.want {
+			t.Errorf("orTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
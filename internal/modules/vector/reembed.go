@@ -0,0 +1,596 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ================================
+// Re-embedding Jobs
+// ================================
+
+const DefaultReembedBatchSize = 200
+
+type ReembedStatus string
+
+const (
+	ReembedRunning   ReembedStatus = "running"
+	ReembedCompleted ReembedStatus = "completed"
+	ReembedFailed    ReembedStatus = "failed"
+	ReembedCancelled ReembedStatus = "cancelled"
+)
+
+// ReembedJob copies a collection into a shadow table with vectors from
+// the target model. Cursor is the last source id copied; a resumed job
+// continues after it.
+type ReembedJob struct {
+	ID         string        `json:"id"`
+	Collection string        `json:"collection"`
+	FromModel  string        `json:"from_model"`
+	ToModel    string        `json:"to_model"`
+	Dimension  int           `json:"dimension"`
+	Status     ReembedStatus `json:"status"`
+	Cursor     string        `json:"cursor"`
+	Processed  int64         `json:"processed"`
+	Total      int64         `json:"total"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// ModelEmbedder is an Embedder that knows its model and output size
+// (embedding.Service implements it).
+type ModelEmbedder interface {
+	Embedder
+	Model() string
+	Dimension() int
+}
+
+var (
+	ErrReembedNotFound   = errors.New("re-embedding job not found")
+	ErrReembedActive     = errors.New("a re-embedding job is already running for this collection")
+	ErrReembedNotRunning = errors.New("re-embedding job is not running")
+	ErrAlreadyEmbedded   = errors.New("collection already uses the target embedding model")
+	ErrReembedBusy       = errors.New("collection kept changing during the swap; resume the job to retry")
+)
+
+type ReembedConfig struct {
+	DB          *sql.DB
+	Collections *CollectionManager
+	Embedder    ModelEmbedder // target model
+	BatchSize   int
+}
+
+type Reembedder struct {
+	db          *sql.DB
+	collections *CollectionManager
+	embed       ModelEmbedder
+	batchSize   int
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+func NewReembedder(cfg ReembedConfig) *Reembedder {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultReembedBatchSize
+	}
+	return &Reembedder{
+		db:          cfg.DB,
+		collections: cfg.Collections,
+		embed:       cfg.Embedder,
+		batchSize:   cfg.BatchSize,
+		running:     make(map[string]context.CancelFunc),
+	}
+}
+
+// Init creates the job table. At most one job per collection may be
+// running at a time.
+func (r *Reembedder) Init(ctx context.Context) error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS vector_reembed_jobs (
+			id TEXT PRIMARY KEY,
+			collection TEXT NOT NULL,
+			from_model TEXT NOT NULL DEFAULT '',
+			to_model TEXT NOT NULL,
+			dimension INT NOT NULL,
+			status TEXT NOT NULL,
+			cursor TEXT NOT NULL DEFAULT '',
+			processed BIGINT NOT NULL DEFAULT 0,
+			total BIGINT NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ DEFAULT NOW(),
+			updated_at TIMESTAMPTZ DEFAULT NOW(),
+			finished_at TIMESTAMPTZ
+		);`,
+
+		`CREATE UNIQUE INDEX IF NOT EXISTS vector_reembed_jobs_active_idx
+		 ON vector_reembed_jobs (collection) WHERE status = 'running';`,
+	}
+
+	for _, q := range queries {
+		if _, err := r.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start re-embeds a collection with the target model in the background.
+func (r *Reembedder) Start(ctx context.Context, collection string) (*ReembedJob, error) {
+	c, err := r.collections.Get(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	if c.Model == r.embed.Model() && c.Dimension == r.embed.Dimension() {
+		return nil, ErrAlreadyEmbedded
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM %s;`, c.Table())).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	job := &ReembedJob{
+		ID:         uuid.New().String(),
+		Collection: c.Name,
+		FromModel:  c.Model,
+		ToModel:    r.embed.Model(),
+		Dimension:  r.embed.Dimension(),
+		Status:     ReembedRunning,
+		Total:      total,
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO vector_reembed_jobs
+		(id, collection, from_model, to_model, dimension, status, total)
+		VALUES ($1,$2,$3,$4,$5,$6,$7);`,
+		job.ID, job.Collection, job.FromModel, job.ToModel, job.Dimension, string(job.Status), job.Total,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrReembedActive
+		}
+		return nil, err
+	}
+
+	r.launch(job)
+	return r.Get(ctx, job.ID)
+}
+
+// Resume restarts a failed or cancelled job from its cursor.
+func (r *Reembedder) Resume(ctx context.Context, id string) (*ReembedJob, error) {
+	job, err := r.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != ReembedFailed && job.Status != ReembedCancelled {
+		return nil, fmt.Errorf("cannot resume a %s job", job.Status)
+	}
+	if job.ToModel != r.embed.Model() || job.Dimension != r.embed.Dimension() {
+		return nil, fmt.Errorf("job targets model %q but %q is configured", job.ToModel, r.embed.Model())
+	}
+
+	res, err := r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+		SET status = 'running', error = '', updated_at = NOW()
+		WHERE id = $1 AND status = $2;`, id, string(job.Status))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrReembedActive
+		}
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrReembedNotRunning
+	}
+
+	job.Status = ReembedRunning
+	r.launch(job)
+	return r.Get(ctx, id)
+}
+
+// Recover relaunches jobs left running by a previous process.
+func (r *Reembedder) Recover(ctx context.Context) error {
+	jobs, err := r.list(ctx, `WHERE status = 'running'`)
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		job := jobs[i]
+		if job.ToModel != r.embed.Model() || job.Dimension != r.embed.Dimension() {
+			r.finish(job.ID, ReembedFailed, fmt.Sprintf("target model %q is no longer configured", job.ToModel))
+			continue
+		}
+		r.launch(&job)
+	}
+	return nil
+}
+
+// Cancel stops a running job. The shadow table is kept so the job can
+// be resumed later.
+func (r *Reembedder) Cancel(ctx context.Context, id string) error {
+	r.mu.Lock()
+	cancel, ok := r.running[id]
+	r.mu.Unlock()
+	if !ok {
+		return ErrReembedNotRunning
+	}
+
+	cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+		SET status = 'cancelled', updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1 AND status = 'running';`, id)
+	return err
+}
+
+func (r *Reembedder) Get(ctx context.Context, id string) (*ReembedJob, error) {
+	jobs, err := r.list(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrReembedNotFound
+	}
+	return &jobs[0], nil
+}
+
+// List returns the jobs of one collection, or of all when empty.
+func (r *Reembedder) List(ctx context.Context, collection string) ([]ReembedJob, error) {
+	if collection == "" {
+		return r.list(ctx, `ORDER BY created_at DESC`)
+	}
+	return r.list(ctx, `WHERE collection = $1 ORDER BY created_at DESC`, collection)
+}
+
+// ================================
+// Runner
+// ================================
+
+func (r *Reembedder) launch(job *ReembedJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r.mu.Lock()
+	r.running[job.ID] = cancel
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			r.mu.Lock()
+			delete(r.running, job.ID)
+			r.mu.Unlock()
+			cancel()
+		}()
+
+		// a cancelled job already has its status written by Cancel
+		if err := r.run(ctx, job); err != nil && ctx.Err() == nil {
+			r.finish(job.ID, ReembedFailed, err.Error())
+		}
+	}()
+}
+
+// run copies the source collection in id order, batch by batch, and
+// records the cursor after each batch. Re-running a batch is harmless
+// because the shadow table is written with upserts.
+func (r *Reembedder) run(ctx context.Context, job *ReembedJob) error {
+	c, err := r.collections.Get(ctx, job.Collection)
+	if err != nil {
+		return err
+	}
+
+	// the ANN index is built once the shadow table is filled
+	target := shadowCollection(*c, job)
+	filling := target
+	filling.Index = IndexNone
+
+	if err := r.resetShadow(ctx, job, target); err != nil {
+		return err
+	}
+	shadow := NewPgVectorCollection(r.db, filling)
+	if err := shadow.Init(ctx); err != nil {
+		return err
+	}
+
+	for {
+		docs, err := readDocuments(ctx, r.db, fmt.Sprintf(`SELECT id, namespace, content, metadata, created_at
+			FROM %s WHERE id > $1 ORDER BY id LIMIT $2;`, c.Table()), job.Cursor, r.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			break
+		}
+
+		if err := r.embedAll(ctx, docs); err != nil {
+			return err
+		}
+		if err := shadow.StoreBatch(ctx, docs); err != nil {
+			return err
+		}
+
+		job.Cursor = docs[len(docs)-1].ID
+		job.Processed += int64(len(docs))
+
+		if _, err := r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+			SET cursor = $2, processed = $3, updated_at = NOW()
+			WHERE id = $1;`, job.ID, job.Cursor, job.Processed); err != nil {
+			return err
+		}
+	}
+
+	if idx := NewPgVectorCollection(r.db, target).indexSQL(); idx != "" {
+		if _, err := r.db.ExecContext(ctx, idx); err != nil {
+			return err
+		}
+	}
+
+	return r.swap(ctx, job, *c, shadow)
+}
+
+// resetShadow drops a shadow table this job cannot resume into: any
+// shadow when the job starts from the beginning, since an earlier job
+// may have left one with another model or dimension, or a missing or
+// differently sized one when it resumes. The copy then restarts.
+func (r *Reembedder) resetShadow(ctx context.Context, job *ReembedJob, target Collection) error {
+	if job.Cursor != "" {
+		// pgvector keeps the dimension as the column's type modifier
+		var dim int
+		err := r.db.QueryRowContext(ctx, `SELECT atttypmod FROM pg_attribute
+			WHERE attrelid = to_regclass($1) AND attname = 'embedding' AND NOT attisdropped;`,
+			target.Table()).Scan(&dim)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil && dim == job.Dimension {
+			return nil
+		}
+
+		log.Printf("re-embed %s: shadow table %s is missing or has another dimension; restarting the copy", job.ID, target.Table())
+		job.Cursor, job.Processed = "", 0
+		if _, err := r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+			SET cursor = '', processed = 0, updated_at = NOW()
+			WHERE id = $1;`, job.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, target.Table()))
+	return err
+}
+
+// maxSwapAttempts bounds how often swap retries a collection that keeps
+// changing between catching up and taking the lock.
+const maxSwapAttempts = 5
+
+// swap makes the shadow table the collection. Rows added or changed
+// since they were copied are re-embedded without blocking writers; only
+// once the shadow table is current is the source locked, dropped and
+// replaced by it, and the registry and live stores switched to the new
+// model.
+func (r *Reembedder) swap(ctx context.Context, job *ReembedJob, c Collection, shadow *PgVectorStore) error {
+	for attempt := 0; attempt < maxSwapAttempts; attempt++ {
+		if err := r.catchUp(ctx, job, c, shadow); err != nil {
+			return err
+		}
+
+		swapped, err := r.rename(ctx, job, c, shadow)
+		if err != nil {
+			return err
+		}
+		if swapped {
+			// the swap is committed; a stale cache only mislabels new writes
+			if err := r.collections.reload(ctx, c.Name); err != nil {
+				log.Printf("re-embed %s: reloading collection %s failed: %v", job.ID, c.Name, err)
+			}
+			return nil
+		}
+	}
+	return ErrReembedBusy
+}
+
+// staleRows selects source rows the shadow table is missing, holds an
+// older version of, or holds with a vector from another model. The
+// target model is bound as $1.
+func staleRows(src, dst string) string {
+	return fmt.Sprintf(`FROM %s s LEFT JOIN %s d ON d.id = s.id
+		WHERE d.id IS NULL
+			OR d.namespace <> s.namespace
+			OR d.content IS DISTINCT FROM s.content
+			OR d.metadata IS DISTINCT FROM s.metadata
+			OR d.embedding_model <> $1`, src, dst)
+}
+
+// catchUp brings the shadow table up to date with writes made to the
+// source since it was copied.
+func (r *Reembedder) catchUp(ctx context.Context, job *ReembedJob, c Collection, shadow *PgVectorStore) error {
+	src, dst := c.Table(), shadow.table
+
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s d
+		WHERE NOT EXISTS (SELECT 1 FROM %s s WHERE s.id = d.id);`, dst, src)); err != nil {
+		return err
+	}
+
+	stale, err := readDocuments(ctx, r.db, `SELECT s.id, s.namespace, s.content, s.metadata, s.created_at `+staleRows(src, dst)+`;`, job.ToModel)
+	if err != nil || len(stale) == 0 {
+		return err
+	}
+
+	if err := r.embedAll(ctx, stale); err != nil {
+		return err
+	}
+	if err := shadow.StoreBatch(ctx, stale); err != nil {
+		return err
+	}
+
+	job.Processed += int64(len(stale))
+	_, err = r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+		SET processed = $2, updated_at = NOW()
+		WHERE id = $1;`, job.ID, job.Processed)
+	return err
+}
+
+// rename swaps the tables under a lock on the source, unless a write
+// landed after the last catch-up; then it reports false and changes
+// nothing. Deletes are cheap to mirror, so only new or changed rows
+// send swap back to catching up.
+func (r *Reembedder) rename(ctx context.Context, job *ReembedJob, c Collection, shadow *PgVectorStore) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	src, dst := c.Table(), shadow.table
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`LOCK TABLE %s IN EXCLUSIVE MODE;`, src)); err != nil {
+		return false, err
+	}
+
+	var stale bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 `+staleRows(src, dst)+`);`, job.ToModel).Scan(&stale); err != nil {
+		return false, err
+	}
+	if stale {
+		return false, nil
+	}
+
+	queries := []string{
+		fmt.Sprintf(`DELETE FROM %s d WHERE NOT EXISTS (SELECT 1 FROM %s s WHERE s.id = d.id);`, dst, src),
+		fmt.Sprintf(`DROP TABLE %s;`, src),
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s;`, dst, src),
+	}
+	for _, suffix := range []string{"pkey", "namespace_idx", "metadata_idx", "content_tsv_idx", "embedding_idx"} {
+		queries = append(queries, fmt.Sprintf(`ALTER INDEX IF EXISTS %s_%s RENAME TO %s_%s;`, dst, suffix, src, suffix))
+	}
+	for _, q := range queries {
+		if _, err := tx.ExecContext(ctx, q); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE vector_collections
+		SET dimension = $2, embedding_model = $3
+		WHERE name = $1;`, c.Name, job.Dimension, job.ToModel); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE vector_reembed_jobs
+		SET status = 'completed', updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1;`, job.ID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+func (r *Reembedder) embedAll(ctx context.Context, docs []Document) error {
+	model := r.embed.Model()
+	for i := range docs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		vec, err := r.embed.Embed(ctx, docs[i].Content)
+		if err != nil {
+			return fmt.Errorf("document %s: %w", docs[i].ID, err)
+		}
+		docs[i].Vector = vec
+		docs[i].Model = model
+	}
+	return nil
+}
+
+// finish records a terminal status. It uses its own context because the
+// job's context may already be cancelled.
+func (r *Reembedder) finish(id string, status ReembedStatus, msg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, _ = r.db.ExecContext(ctx, `UPDATE vector_reembed_jobs
+		SET status = $2, error = $3, updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1;`, id, string(status), msg)
+}
+
+// ================================
+// Helpers
+// ================================
+
+func shadowCollection(c Collection, job *ReembedJob) Collection {
+	c.Name += "_shadow"
+	c.Dimension = job.Dimension
+	c.Model = job.ToModel
+	return c
+}
+
+func (r *Reembedder) list(ctx context.Context, clause string, args ...interface{}) ([]ReembedJob, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, collection, from_model, to_model, dimension, status,
+		cursor, processed, total, error, created_at, updated_at, finished_at
+		FROM vector_reembed_jobs `+clause+`;`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ReembedJob
+	for rows.Next() {
+		var j ReembedJob
+		var status string
+		var createdAt, updatedAt, finishedAt sql.NullTime
+
+		if err := rows.Scan(&j.ID, &j.Collection, &j.FromModel, &j.ToModel, &j.Dimension, &status,
+			&j.Cursor, &j.Processed, &j.Total, &j.Error, &createdAt, &updatedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+
+		j.Status = ReembedStatus(status)
+		j.CreatedAt = createdAt.Time
+		j.UpdatedAt = updatedAt.Time
+		if finishedAt.Valid {
+			t := finishedAt.Time
+			j.FinishedAt = &t
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// readDocuments reads (id, namespace, content, metadata, created_at)
+// rows without their vectors.
+func readDocuments(ctx context.Context, q queryer, query string, args ...interface{}) ([]Document, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var docs []Document
+	for rows.Next() {
+		var doc Document
+		var content sql.NullString
+		var metaJSON []byte
+		var createdAt sql.NullTime
+
+		if err := rows.Scan(&doc.ID, &doc.Namespace, &content, &metaJSON, &createdAt); err != nil {
+			return nil, err
+		}
+
+		meta, err := decodeMeta(metaJSON)
+		if err != nil {
+			return nil, err
+		}
+		doc.Content = content.String
+		doc.Meta = meta
+		doc.CreatedAt = createdAt.Time
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
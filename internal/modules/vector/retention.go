@@ -0,0 +1,183 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ================================
+// Retention Policies
+// ================================
+
+// Metadata keys the retention rules read.
+const (
+	metaType       = "type"
+	metaUser       = "userID"
+	metaImportance = "importance"
+)
+
+// RetentionPolicy applies to documents whose Meta["type"] equals Type.
+// Zero values disable a rule. MaxPerUser keeps the newest documents per
+// Meta["userID"] (documents without one share a single bucket), and
+// MinImportance only evicts documents that carry a numeric importance.
+type RetentionPolicy struct {
+	Type          string        `json:"type"`
+	MaxAge        time.Duration `json:"max_age"`
+	MaxPerUser    int           `json:"max_per_user"`
+	MinImportance float64       `json:"min_importance"`
+}
+
+func (p RetentionPolicy) Validate() error {
+	if p.Type == "" {
+		return errors.New("retention policy requires a document type")
+	}
+	if p.MaxAge < 0 || p.MaxPerUser < 0 || p.MinImportance < 0 {
+		return errors.New("retention limits must not be negative")
+	}
+	return nil
+}
+
+// EvictionStats counts the documents one policy removed, per rule. A
+// document is counted under the first rule that removed it.
+type EvictionStats struct {
+	Type          string `json:"type"`
+	Expired       int64  `json:"expired"`
+	OverLimit     int64  `json:"over_limit"`
+	LowImportance int64  `json:"low_importance"`
+}
+
+func (s EvictionStats) Total() int64 {
+	return s.Expired + s.OverLimit + s.LowImportance
+}
+
+// Compactor is implemented by stores that can enforce a retention
+// policy themselves.
+type Compactor interface {
+	Compact(ctx context.Context, policy RetentionPolicy, now time.Time) (EvictionStats, error)
+}
+
+// MetaNumber reads a numeric metadata value, whether it was set in Go
+// or decoded from JSON.
+func MetaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// ================================
+// Compaction Worker
+// ================================
+
+const DefaultRetentionInterval = time.Hour
+
+type RetentionReport struct {
+	StartedAt time.Time       `json:"started_at"`
+	Took      time.Duration   `json:"took"`
+	Evicted   int64           `json:"evicted"`
+	Policies  []EvictionStats `json:"policies"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type RetentionConfig struct {
+	Store    Store // must implement Compactor
+	Policies []RetentionPolicy
+	Interval time.Duration
+}
+
+type RetentionWorker struct {
+	store    Compactor
+	policies []RetentionPolicy
+	interval time.Duration
+
+	mu   sync.Mutex // serializes runs and guards last
+	last *RetentionReport
+}
+
+func NewRetentionWorker(cfg RetentionConfig) (*RetentionWorker, error) {
+	c, ok := cfg.Store.(Compactor)
+	if !ok {
+		return nil, errors.New("vector store does not support retention policies")
+	}
+	for _, p := range cfg.Policies {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultRetentionInterval
+	}
+
+	return &RetentionWorker{
+		store:    c,
+		policies: cfg.Policies,
+		interval: cfg.Interval,
+	}, nil
+}
+
+// Start runs a compaction every interval until ctx is cancelled.
+func (w *RetentionWorker) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := w.RunOnce(ctx)
+				if report.Error != "" {
+					log.Printf("vector retention: %s", report.Error)
+				} else {
+					log.Printf("vector retention: evicted %d documents in %s", report.Evicted, report.Took)
+				}
+			}
+		}
+	}()
+}
+
+// RunOnce applies every policy in order. A failing policy is reported
+// and the remaining ones still run.
+func (w *RetentionWorker) RunOnce(ctx context.Context) RetentionReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	start := time.Now()
+	report := RetentionReport{StartedAt: start}
+
+	for _, p := range w.policies {
+		stats, err := w.store.Compact(ctx, p, start)
+		if err != nil {
+			if report.Error != "" {
+				report.Error += "; "
+			}
+			report.Error += p.Type + ": " + err.Error()
+		}
+		stats.Type = p.Type
+		report.Policies = append(report.Policies, stats)
+		report.Evicted += stats.Total()
+	}
+
+	report.Took = time.Since(start)
+	w.last = &report
+	return report
+}
+
+// Last returns the most recent report, or nil before the first run.
+func (w *RetentionWorker) Last() *RetentionReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
@@ -1,3 +1,219 @@
 package vector
 
-// TODO: vector store interface + factory
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ================================
+// Data Structures
+// ================================
+
+type Document struct {
+	ID        string                 `json:"id"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Content   string                 `json:"content"`
+	Vector    []float32              `json:"vector,omitempty"`
+	Meta      map[string]interface{} `json:"meta"`
+	CreatedAt time.Time              `json:"created_at"`
+
+	// embedding provenance; stores stamp their own model when Model is empty
+	Model     string `json:"embedding_model,omitempty"`
+	Dimension int    `json:"embedding_dim,omitempty"`
+
+	// set on search results only; always encoded, since 0 is a real
+	// distance (an exact match) and a real score
+	Distance float64 `json:"distance"`
+	Score    float64 `json:"score"`
+}
+
+// ================================
+// Search Options
+// ================================
+
+type Metric string
+
+const (
+	MetricL2           Metric = "l2"
+	MetricCosine       Metric = "cosine"
+	MetricInnerProduct Metric = "inner_product"
+)
+
+// Filter restricts a search to documents whose metadata matches.
+// All conditions are ANDed; an empty Namespace matches every namespace
+// unless ExactNamespace is set, which pins it to the default one.
+type Filter struct {
+	Namespace      string
+	ExactNamespace bool
+	Equals         map[string]interface{}
+	In             map[string][]interface{}
+	NotEquals      map[string]interface{}
+}
+
+func (f Filter) IsEmpty() bool {
+	return f.Namespace == "" && !f.ExactNamespace && len(f.Equals) == 0 && len(f.In) == 0 && len(f.NotEquals) == 0
+}
+
+type SearchOptions struct {
+	Filter
+
+	Limit    int
+	MinScore float64 // similarity in the metric's score space, see Score
+	Metric   Metric  // empty = the store's default metric
+
+	IncludeVectors bool // return the stored embedding with each hit
+}
+
+func (f Filter) Match(doc Document) bool {
+	if (f.Namespace != "" || f.ExactNamespace) && doc.Namespace != f.Namespace {
+		return false
+	}
+	for k, v := range f.Equals {
+		if got, ok := doc.Meta[k]; !ok || !jsonEqual(got, v) {
+			return false
+		}
+	}
+	for k, vals := range f.In {
+		got, ok := doc.Meta[k]
+		if !ok {
+			return false
+		}
+		found := false
+		for _, v := range vals {
+			if jsonEqual(got, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range f.NotEquals {
+		if got, ok := doc.Meta[k]; ok && jsonEqual(got, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares metadata values the way JSONB does, so that e.g.
+// int 3 and float64 3 (what a decoded document holds) are equal.
+func jsonEqual(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// Score converts a raw distance into a similarity where higher is better:
+// l2 -> 1/(1+d), cosine -> 1-d (cosine similarity), inner product -> dot.
+func Score(metric Metric, distance float64) float64 {
+	switch metric {
+	case MetricCosine:
+		return 1 - distance
+	case MetricInnerProduct:
+		return -distance
+	default:
+		return 1 / (1 + distance)
+	}
+}
+
+// maxDistance is the inverse of Score, used to push MinScore into queries.
+func maxDistance(metric Metric, minScore float64) float64 {
+	switch metric {
+	case MetricCosine:
+		return 1 - minScore
+	case MetricInnerProduct:
+		return -minScore
+	default:
+		return 1/minScore - 1
+	}
+}
+
+// ================================
+// Store Interface
+// ================================
+
+type Store interface {
+	Init(ctx context.Context) error
+	Store(ctx context.Context, doc Document) error
+	StoreBatch(ctx context.Context, docs []Document) error
+	Search(ctx context.Context, vector []float32, opts SearchOptions) ([]Document, error)
+	Delete(ctx context.Context, id string) error
+	DeleteWhere(ctx context.Context, f Filter) (int64, error)
+}
+
+// MetaUpdater is implemented by stores that can merge keys into the
+// metadata of existing documents without rewriting their vectors.
+type MetaUpdater interface {
+	UpdateMeta(ctx context.Context, ids []string, patch map[string]interface{}) error
+}
+
+// Embedder turns text into a vector (implemented by llm.Manager).
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// ================================
+// Factory
+// ================================
+
+const (
+	BackendPgVector = "pgvector"
+	BackendHNSW     = "hnsw"
+)
+
+type Config struct {
+	Backend   string
+	Dimension int
+	Metric    Metric
+	Model     string // embedding model stamped on every stored document
+
+	// pgvector
+	DB *sql.DB
+
+	// hnsw
+	Path           string
+	SaveInterval   time.Duration
+	M              int
+	EfConstruction int
+	EfSearch       int
+}
+
+func New(cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case "", BackendPgVector:
+		if cfg.DB == nil {
+			return nil, errors.New("pgvector backend requires a database")
+		}
+		return NewPgVectorCollection(cfg.DB, Collection{
+			Name:      DefaultCollection,
+			Dimension: cfg.Dimension,
+			Metric:    cfg.Metric,
+			Model:     cfg.Model,
+		}), nil
+	case BackendHNSW:
+		return NewHNSWStore(HNSWConfig{
+			Dimension:      cfg.Dimension,
+			M:              cfg.M,
+			EfConstruction: cfg.EfConstruction,
+			EfSearch:       cfg.EfSearch,
+			Metric:         cfg.Metric,
+			Model:          cfg.Model,
+			Path:           cfg.Path,
+			SaveInterval:   cfg.SaveInterval,
+		}), nil
+	default:
+		return nil, errors.New("unsupported vector backend: " + cfg.Backend)
+	}
+}
@@ -0,0 +1,42 @@
+package vector
+
+import "testing"
+
+func TestFilterMatchNamespace(t *testing.T) {
+	def := Document{ID: "a", Meta: map[string]interface{}{}}
+	team := Document{ID: "b", Namespace: "team", Meta: map[string]interface{}{}}
+
+	cases := []struct {
+		name   string
+		filter Filter
+		def    bool
+		team   bool
+	}{
+		{"any namespace", Filter{}, true, true},
+		{"named", Filter{Namespace: "team"}, false, true},
+		{"default only", Filter{ExactNamespace: true}, true, false},
+		{"named exact", Filter{Namespace: "team", ExactNamespace: true}, false, true},
+	}
+	for _, tc := range cases {
+		if got := tc.filter.Match(def); got != tc.def {
+			t.Errorf("%s: default namespace matched = %v, want %v", tc.name, got, tc.def)
+		}
+		if got := tc.filter.Match(team); got != tc.team {
+			t.Errorf("%s: team namespace matched = %v, want %v", tc.name, got, tc.team)
+		}
+	}
+
+	if (Filter{ExactNamespace: true}).IsEmpty() {
+		t.Error("a filter pinned to the default namespace is not empty")
+	}
+}
+
+func TestFilterToSQLExactNamespace(t *testing.T) {
+	where, args, err := filterToSQL(Filter{ExactNamespace: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(where) != 1 || where[0] != "namespace = $1" || len(args) != 1 || args[0] != "" {
+		t.Fatalf("got %v %v, want namespace = '' bound as $1", where, args)
+	}
+}